@@ -0,0 +1,78 @@
+package wordlist
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func abandonedQuizSweeper(db *gorm.DB, config QuizConfig) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := expireOngoingQuizzes(db.WithContext(ctx), time.Now().Add(-config.AbandonAfter), config.RecordAbandoned)
+		return err
+	}
+}
+
+func sweepInterval(ttl time.Duration) time.Duration {
+	interval := ttl / 4
+	if interval < time.Minute {
+		return time.Minute
+	}
+	if interval > time.Hour {
+		return time.Hour
+	}
+	return interval
+}
+
+// expireOngoingQuizzes removes ongoing quizzes untouched since cutoff, optionally recording them as abandoned
+func expireOngoingQuizzes(db *gorm.DB, cutoff time.Time, recordAbandoned bool) (int, error) {
+	expired := []OngoingQuiz{}
+	result := db.Preload("OngoingQuizQuestions").Where("coalesce(updated_at, created_at) < ?", cutoff).Find(&expired)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	count := 0
+	for _, oq := range expired {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("session = ?", oq.Session).Delete(&OngoingQuiz{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				// submitted in the meantime
+				return nil
+			}
+			result = tx.Where("ongoing_quiz_id = ?", oq.Session).Delete(OngoingQuizQuestion{})
+			if result.Error != nil {
+				return result.Error
+			}
+			if recordAbandoned {
+				result = tx.Create(&CompletedQuiz{
+					Session:           oq.Session,
+					UserID:            oq.UserID,
+					TakenAt:           oq.CreatedAt,
+					Direction:         oq.Direction,
+					Format:            oq.Format,
+					Tolerance:         oq.Tolerance,
+					MaxDistance:       oq.MaxDistance,
+					TotalQuestions:    len(oq.OngoingQuizQuestions),
+					TimeLimit:         oq.TimeLimit,
+					QuestionTimeLimit: oq.QuestionTimeLimit,
+					Abandoned:         true,
+					ChallengeCode:     oq.ChallengeCode,
+					AssignmentID:      oq.AssignmentID,
+				})
+				if result.Error != nil {
+					return result.Error
+				}
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
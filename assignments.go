@@ -0,0 +1,439 @@
+package wordlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	assignmentNotStarted = "not started"
+	assignmentInProgress = "in progress"
+	assignmentCompleted  = "completed"
+)
+
+var (
+	errAssignmentNotFound = errors.New("assignment not found")
+	errInvalidAssignment  = errors.New("invalid assignment")
+)
+
+func setupAssignments(ws *website.Website, config QuizConfig, quizAPIGET *mux.Router) {
+	if err := ws.DB().AutoMigrate(&Assignment{}); err != nil {
+		panic(err)
+	}
+
+	assignmentsHTML, err := ws.WebsiteContent().ReadFile("web/html/assignments.html")
+	if err != nil {
+		panic(err)
+	}
+
+	assignments := ws.Router().Path("/assignments/").Methods("GET").Subrouter()
+	assignments.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{UserNames: authorizedUsers}))
+	assignments.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(assignmentsHTML)
+	})
+
+	assignmentsAPI := ws.Router().PathPrefix("/assignments-api/").Methods("GET").Subrouter()
+	assignmentsAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{UserNames: authorizedUsers, IsForAPI: true}))
+	assignmentsAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		assigned := []Assignment{}
+		result := ws.DB().Preload("Words").Preload("Learners").
+			Where("assigned_by = ?", ws.AuthenticatedUser(r).ID).Order("due_at desc").Find(&assigned)
+		if result.Error != nil {
+			log.Printf("error reading assignments from DB: %v", result.Error)
+			http.Error(w, "unable to read assignments", http.StatusInternalServerError)
+			return
+		}
+		reports, err := assignmentReports(ws.DB(), assigned, time.Now())
+		if err != nil {
+			log.Printf("error reading assignment status from DB: %v", err)
+			http.Error(w, "unable to read assignments", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(reports); err != nil {
+			log.Printf("error encoding assignments: %v", err)
+			http.Error(w, "unable to read assignments", http.StatusInternalServerError)
+			return
+		}
+	})
+	assignmentsAPI.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		a, err := assignedBy(ws.DB(), ws.AuthenticatedUser(r), mux.Vars(r)["id"])
+		if err != nil {
+			quizError(w, err, "unable to read assignment")
+			return
+		}
+		reports, err := assignmentReports(ws.DB(), []Assignment{*a}, time.Now())
+		if err != nil {
+			log.Printf("error reading assignment status from DB: %v", err)
+			http.Error(w, "unable to read assignment", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(reports[0]); err != nil {
+			log.Printf("error encoding assignment: %v", err)
+			http.Error(w, "unable to read assignment", http.StatusInternalServerError)
+			return
+		}
+	})
+
+	assignmentsPOSTAPI := ws.Router().PathPrefix("/assignments-api/").Methods("POST").Subrouter()
+	assignmentsPOSTAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{UserNames: authorizedUsers, IsForAPI: true}))
+	assignmentsPOSTAPI.HandleFunc("/new/", func(w http.ResponseWriter, r *http.Request) {
+		req := AssignmentRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "assignment must be sent as JSON", http.StatusBadRequest)
+			return
+		}
+		opts, err := parseQuizOptions(r.URL.Query(), config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a, err := newAssignment(ws.DB(), ws.AuthenticatedUser(r), req, opts, config)
+		if errors.Is(err, errInvalidAssignment) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("error creating assignment: %v", err)
+			http.Error(w, "unable to create assignment", http.StatusInternalServerError)
+			return
+		}
+		reports, err := assignmentReports(ws.DB(), []Assignment{*a}, time.Now())
+		if err != nil {
+			log.Printf("error reading assignment status from DB: %v", err)
+			http.Error(w, "unable to create assignment", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(reports[0]); err != nil {
+			log.Printf("error encoding assignment: %v", err)
+			http.Error(w, "unable to create assignment", http.StatusInternalServerError)
+			return
+		}
+	})
+	assignmentsPOSTAPI.HandleFunc("/delete/{id}", func(w http.ResponseWriter, r *http.Request) {
+		a, err := assignedBy(ws.DB(), ws.AuthenticatedUser(r), mux.Vars(r)["id"])
+		if err != nil {
+			quizError(w, err, "unable to delete assignment")
+			return
+		}
+		// quizzes already taken for it are kept, as the learners' own scores
+		if err := ws.DB().Select("Words", "Learners").Delete(a).Error; err != nil {
+			log.Printf("error deleting assignment: %v", err)
+			http.Error(w, "unable to delete assignment", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte("{}"))
+	})
+
+	quizAPIGET.HandleFunc("/assignments/", func(w http.ResponseWriter, r *http.Request) {
+		pending, err := pendingAssignments(ws.DB(), ws.AuthenticatedUser(r), time.Now())
+		if err != nil {
+			log.Printf("error reading pending assignments from DB: %v", err)
+			http.Error(w, "unable to read assignments", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(pending); err != nil {
+			log.Printf("error encoding pending assignments: %v", err)
+			http.Error(w, "unable to read assignments", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+func newAssignment(db *gorm.DB, user *website.User, req AssignmentRequest, opts quizOptions, config QuizConfig) (*Assignment, error) {
+	if opts.Source != sourceAll || opts.Challenge != "" || opts.Assignment != 0 || len(opts.Tags) > 0 {
+		return nil, fmt.Errorf("%w: an assignment quizzes its own words", errInvalidAssignment)
+	}
+	if req.DueAt.IsZero() {
+		return nil, fmt.Errorf("%w: a due date is needed", errInvalidAssignment)
+	}
+	words, err := wordIndex.byIDs(db, req.WordIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) < config.MinQuestions || len(words) > config.MaxQuestions {
+		return nil, fmt.Errorf("%w: pick between %d and %d words", errInvalidAssignment, config.MinQuestions, config.MaxQuestions)
+	}
+	if len(req.Learners) == 0 {
+		return nil, fmt.Errorf("%w: assign it to at least one learner", errInvalidAssignment)
+	}
+	learners := []website.User{}
+	if result := db.Where("username IN ?", req.Learners).Find(&learners); result.Error != nil {
+		return nil, result.Error
+	}
+	for _, name := range req.Learners {
+		found := false
+		for _, l := range learners {
+			found = found || l.Username == name
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown learner %s", errInvalidAssignment, name)
+		}
+	}
+	a := &Assignment{
+		AssignedBy: user.ID,
+		Title:      strings.TrimSpace(req.Title),
+		Options:    strings.TrimPrefix(opts.query(), "?"),
+		DueAt:      req.DueAt,
+		Words:      words,
+		Learners:   learners,
+	}
+	if a.Title == "" {
+		a.Title = fmt.Sprintf("%d words", len(words))
+	}
+	// the words and learners exist already; only the links to them are new
+	return a, db.Omit("Words.*", "Learners.*").Create(a).Error
+}
+
+// assignedBy is an assignment the user made
+func assignedBy(db *gorm.DB, user *website.User, idStr string) (*Assignment, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, errAssignmentNotFound
+	}
+	a := &Assignment{}
+	result := db.Preload("Words").Preload("Learners").Limit(1).Find(a, "id = ? AND assigned_by = ?", id, user.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errAssignmentNotFound
+	}
+	return a, nil
+}
+
+// assignmentOptions are the settings of an assignment for one of its learners to take it with
+func assignmentOptions(db *gorm.DB, user *website.User, id int, config QuizConfig) (quizOptions, error) {
+	a := &Assignment{}
+	result := db.Preload("Words").
+		Joins("join assignment_learners on assignment_learners.assignment_id = assignments.id").
+		Where("assignments.id = ? AND assignment_learners.user_id = ?", id, user.ID).
+		Limit(1).Find(a)
+	if result.Error != nil {
+		return quizOptions{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return quizOptions{}, errAssignmentNotFound
+	}
+	v, err := url.ParseQuery(a.Options)
+	if err != nil {
+		return quizOptions{}, err
+	}
+	opts, err := parseQuizOptions(v, config)
+	if err != nil {
+		return quizOptions{}, err
+	}
+	opts.Source = sourceAssignment
+	opts.Assignment = id
+	// the cached words have their senses, to ask any of them and keep the others out of the choices
+	ids := make([]int, 0, len(a.Words))
+	for _, w := range a.Words {
+		ids = append(ids, int(w.ID))
+	}
+	if opts.words, err = wordIndex.byIDs(db, ids); err != nil {
+		return quizOptions{}, err
+	}
+	return opts, nil
+}
+
+// assignmentReports has where each learner is with each of the assignments
+func assignmentReports(db *gorm.DB, assignments []Assignment, now time.Time) ([]AssignmentReport, error) {
+	reports := make([]AssignmentReport, 0, len(assignments))
+	if len(assignments) == 0 {
+		return reports, nil
+	}
+	ids := make([]uint, 0, len(assignments))
+	for _, a := range assignments {
+		ids = append(ids, a.ID)
+	}
+	attempts, err := assignmentAttempts(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assignments {
+		report := AssignmentReport{
+			ID:        a.ID,
+			Title:     a.Title,
+			Options:   a.Options,
+			DueAt:     a.DueAt,
+			Questions: len(a.Words),
+			Learners:  make([]LearnerStatus, 0, len(a.Learners)),
+		}
+		for _, l := range a.Learners {
+			status := attempts.status(a, l.ID, now)
+			status.Username = l.Username
+			report.Learners = append(report.Learners, status)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// pendingAssignments are the user's assignments they haven't completed yet, soonest due first
+func pendingAssignments(db *gorm.DB, user *website.User, now time.Time) ([]PendingAssignment, error) {
+	assigned := []Assignment{}
+	result := db.Preload("Words").
+		Joins("join assignment_learners on assignment_learners.assignment_id = assignments.id").
+		Where("assignment_learners.user_id = ?", user.ID).
+		Order("due_at").Find(&assigned)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	pending := []PendingAssignment{}
+	if len(assigned) == 0 {
+		return pending, nil
+	}
+	ids, assignerIDs := make([]uint, 0, len(assigned)), make([]uint, 0, len(assigned))
+	for _, a := range assigned {
+		ids = append(ids, a.ID)
+		assignerIDs = append(assignerIDs, a.AssignedBy)
+	}
+	attempts, err := assignmentAttempts(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	assigners := []website.User{}
+	if result := db.Where("id IN ?", assignerIDs).Find(&assigners); result.Error != nil {
+		return nil, result.Error
+	}
+	usernames := map[uint]string{}
+	for _, u := range assigners {
+		usernames[u.ID] = u.Username
+	}
+	for _, a := range assigned {
+		status := attempts.status(a, user.ID, now)
+		if status.Status == assignmentCompleted {
+			continue
+		}
+		pending = append(pending, PendingAssignment{
+			ID:         a.ID,
+			Title:      a.Title,
+			AssignedBy: usernames[a.AssignedBy],
+			DueAt:      a.DueAt,
+			Overdue:    status.Overdue,
+			Questions:  len(a.Words),
+			Status:     status.Status,
+			URL:        fmt.Sprintf("/quiz/%d?assignment=%d", len(a.Words), a.ID),
+		})
+	}
+	return pending, nil
+}
+
+// attemptsByLearner are the quizzes taken or being taken for assignments
+type attemptsByLearner struct {
+	completed map[uint]map[uint]CompletedQuiz // by assignment then user, the first completed
+	ongoing   map[uint]map[uint]bool
+}
+
+func assignmentAttempts(db *gorm.DB, ids []uint) (attemptsByLearner, error) {
+	attempts := attemptsByLearner{completed: map[uint]map[uint]CompletedQuiz{}, ongoing: map[uint]map[uint]bool{}}
+	completed := []CompletedQuiz{}
+	result := db.Where("assignment_id IN ? AND (abandoned IS NULL OR NOT abandoned)", ids).Order("taken_at").Find(&completed)
+	if result.Error != nil {
+		return attempts, result.Error
+	}
+	for _, cq := range completed {
+		if attempts.completed[cq.AssignmentID] == nil {
+			attempts.completed[cq.AssignmentID] = map[uint]CompletedQuiz{}
+		}
+		if _, ok := attempts.completed[cq.AssignmentID][cq.UserID]; !ok {
+			attempts.completed[cq.AssignmentID][cq.UserID] = cq
+		}
+	}
+	ongoing := []OngoingQuiz{}
+	if result := db.Select("session, user_id, assignment_id").Where("assignment_id IN ?", ids).Find(&ongoing); result.Error != nil {
+		return attempts, result.Error
+	}
+	for _, oq := range ongoing {
+		if attempts.ongoing[oq.AssignmentID] == nil {
+			attempts.ongoing[oq.AssignmentID] = map[uint]bool{}
+		}
+		attempts.ongoing[oq.AssignmentID][oq.UserID] = true
+	}
+	return attempts, nil
+}
+
+func (attempts attemptsByLearner) status(a Assignment, userID uint, now time.Time) LearnerStatus {
+	if cq, ok := attempts.completed[a.ID][userID]; ok {
+		takenAt := cq.TakenAt
+		return LearnerStatus{
+			Status:         assignmentCompleted,
+			Session:        cq.Session,
+			CompletedAt:    &takenAt,
+			Late:           cq.TakenAt.After(a.DueAt),
+			Score:          cq.Score,
+			TotalQuestions: cq.TotalQuestions,
+		}
+	}
+	status := LearnerStatus{Status: assignmentNotStarted, Overdue: now.After(a.DueAt)}
+	if attempts.ongoing[a.ID][userID] {
+		status.Status = assignmentInProgress
+	}
+	return status
+}
+
+// Assignment is a set of words to be quizzed on with the given settings, set for learners to do by a due date
+type Assignment struct {
+	ID         uint `gorm:"primaryKey"`
+	AssignedBy uint `gorm:"index"`
+	Title      string
+	Options    string // quiz settings, encoded as for the quiz page
+	DueAt      time.Time
+	CreatedAt  time.Time
+	Words      []Word         `gorm:"many2many:assignment_words"`
+	Learners   []website.User `gorm:"many2many:assignment_learners"`
+}
+
+type AssignmentRequest struct {
+	Title    string
+	WordIDs  []int
+	Learners []string // usernames
+	DueAt    time.Time
+}
+
+type AssignmentReport struct {
+	ID        uint
+	Title     string
+	Options   string
+	DueAt     time.Time
+	Questions int
+	Learners  []LearnerStatus
+}
+
+type LearnerStatus struct {
+	Username       string
+	Status         string
+	Overdue        bool       `json:",omitempty"` // not completed and past its due date
+	Session        string     `json:",omitempty"` // of the completed quiz
+	CompletedAt    *time.Time `json:",omitempty"`
+	Late           bool       `json:",omitempty"` // completed after its due date
+	Score          float64
+	TotalQuestions int
+}
+
+type PendingAssignment struct {
+	ID         uint
+	Title      string
+	AssignedBy string
+	DueAt      time.Time
+	Overdue    bool
+	Questions  int
+	Status     string
+	URL        string // to take it
+}
@@ -0,0 +1,428 @@
+package wordlist
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	challengeCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // no 0/O or 1/I to mix up when read out
+	challengeCodeLength   = 6
+)
+
+var (
+	errChallengeNotFound    = errors.New("challenge not found")
+	errChallengeUnavailable = errors.New("a challenge can't be made from this quiz")
+)
+
+func setupChallenges(ws *website.Website, config QuizConfig, quizAPIGET, quizAPIPOST *mux.Router) {
+	if err := ws.DB().AutoMigrate(&Challenge{}); err != nil {
+		panic(err)
+	}
+	if err := ws.DB().AutoMigrate(&ChallengeQuestion{}); err != nil {
+		panic(err)
+	}
+
+	challengeHTML, err := ws.WebsiteContent().ReadFile("web/html/challenge.html")
+	if err != nil {
+		panic(err)
+	}
+
+	challenge := ws.Router().PathPrefix("/challenge/").Methods("GET").Subrouter()
+	challenge.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{}))
+	challenge.HandleFunc("/{code}", func(w http.ResponseWriter, r *http.Request) {
+		c, err := loadChallenge(ws.DB(), mux.Vars(r)["code"])
+		if err != nil {
+			quizError(w, err, "error opening challenge")
+			return
+		}
+		url := fmt.Sprintf("/quiz/%d?challenge=%s", len(c.ChallengeQuestions), c.Code)
+		http.Redirect(w, r, url, http.StatusSeeOther)
+	})
+	challenge.HandleFunc("/{code}/results", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(challengeHTML)
+	})
+
+	quizAPIPOST.HandleFunc("/challenge/from/{session}", func(w http.ResponseWriter, r *http.Request) {
+		c, err := challengeFromQuiz(ws.DB(), ws.AuthenticatedUser(r), mux.Vars(r)["session"])
+		if err != nil {
+			quizError(w, err, "error creating challenge")
+			return
+		}
+		writeChallengeInfo(w, c)
+	})
+
+	quizAPIPOST.HandleFunc("/challenge/new/{count_str}", func(w http.ResponseWriter, r *http.Request) {
+		count, err := config.quizLength(mux.Vars(r)["count_str"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts, err := parseQuizOptions(r.URL.Query(), config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c, err := newChallenge(ws.DB(), ws.AuthenticatedUser(r), count, opts, config)
+		if err != nil {
+			quizError(w, err, "error creating challenge")
+			return
+		}
+		writeChallengeInfo(w, c)
+	})
+
+	quizAPIGET.HandleFunc("/challenge/{code}/results", func(w http.ResponseWriter, r *http.Request) {
+		results, err := challengeResults(ws.DB(), mux.Vars(r)["code"])
+		if err != nil {
+			quizError(w, err, "error reading challenge results")
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Printf("error encoding challenge results: %v", err)
+			http.Error(w, "error reading challenge results", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+func writeChallengeInfo(w http.ResponseWriter, c *Challenge) {
+	w.Header().Set("Content-Type", "application/javascript")
+	err := json.NewEncoder(w).Encode(ChallengeInfo{
+		Code:      c.Code,
+		URL:       "/challenge/" + c.Code,
+		Direction: c.Direction,
+		Format:    c.Format,
+		Questions: len(c.ChallengeQuestions),
+	})
+	if err != nil {
+		log.Printf("error encoding challenge: %v", err)
+		http.Error(w, "error creating challenge", http.StatusInternalServerError)
+		return
+	}
+}
+
+// challengeFromQuiz makes a challenge of the questions of one of the user's completed quizzes, as they were asked
+func challengeFromQuiz(db *gorm.DB, user *website.User, session string) (*Challenge, error) {
+	cq := &CompletedQuiz{}
+	result := db.Limit(1).Find(cq, "session = ? AND user_id = ?", session, user.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errQuizNotFound
+	}
+	if cq.Abandoned {
+		return nil, fmt.Errorf("%w: it was abandoned", errChallengeUnavailable)
+	}
+	answers := []CompletedQuizAnswer{}
+	if result := db.Where("session = ?", session).Order("position").Find(&answers); result.Error != nil {
+		return nil, result.Error
+	}
+	if len(answers) == 0 {
+		return nil, fmt.Errorf("%w: its questions weren't recorded", errChallengeUnavailable)
+	}
+	c := &Challenge{
+		CreatedBy:         user.ID,
+		FromSession:       session,
+		Direction:         cq.Direction,
+		Format:            cq.Format,
+		Tolerance:         cq.Tolerance,
+		MaxDistance:       cq.MaxDistance,
+		Feedback:          cq.Feedback,
+		TimeLimit:         cq.TimeLimit,
+		QuestionTimeLimit: cq.QuestionTimeLimit,
+	}
+	if c.Format == formatTyped && c.Tolerance == "" {
+		// quizzes completed before tolerance was recorded were graded with the defaults
+		c.Tolerance, c.MaxDistance = toleranceFuzzy, defaultMaxDistance
+	}
+	for _, a := range answers {
+		word, meaning := a.Prompt, a.Expected
+		if cq.Direction == directionReverse {
+			word, meaning = a.Expected, a.Prompt
+		}
+		c.ChallengeQuestions = append(c.ChallengeQuestions, ChallengeQuestion{
+			Position: a.Position,
+			WordID:   a.WordID,
+			SenseID:  a.SenseID,
+			Word:     word,
+			Meaning:  meaning,
+			Choices:  a.Choices,
+		})
+	}
+	return c, createChallenge(db, c)
+}
+
+// newChallenge makes a challenge of freshly picked questions, picked with a recorded seed
+func newChallenge(db *gorm.DB, user *website.User, count int, opts quizOptions, config QuizConfig) (*Challenge, error) {
+	qs, err := newQuizSession(db, config, opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+	seed := time.Now().UnixNano()
+	qs.rnd = mathrand.New(mathrand.NewSource(seed))
+	questions, err := qs.questions(count, user, opts)
+	if err != nil {
+		return nil, err
+	}
+	c := &Challenge{
+		CreatedBy:         user.ID,
+		Seed:              seed,
+		Direction:         opts.Direction,
+		Format:            opts.Format,
+		Tolerance:         opts.Tolerance,
+		MaxDistance:       opts.MaxDistance,
+		Feedback:          opts.Feedback,
+		TimeLimit:         opts.TimeLimit,
+		QuestionTimeLimit: opts.QuestionTimeLimit,
+	}
+	for position, q := range questions {
+		c.ChallengeQuestions = append(c.ChallengeQuestions, ChallengeQuestion{
+			Position: position,
+			WordID:   q.WordID,
+			SenseID:  q.SenseID,
+			Word:     q.Word,
+			Meaning:  q.Meaning,
+			Choices:  q.Choices,
+		})
+	}
+	return c, createChallenge(db, c)
+}
+
+// createChallenge saves the challenge under a new short code
+func createChallenge(db *gorm.DB, c *Challenge) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := challengeCode()
+		if err != nil {
+			return err
+		}
+		result := db.Model(&Challenge{}).Where("code = ?", code).Limit(1).Find(&Challenge{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			continue
+		}
+		c.Code = code
+		return db.Create(c).Error
+	}
+	return fmt.Errorf("unable to find a free challenge code")
+}
+
+func challengeCode() (string, error) {
+	code := make([]byte, challengeCodeLength)
+	max := big.NewInt(int64(len(challengeCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = challengeCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func loadChallenge(db *gorm.DB, code string) (*Challenge, error) {
+	c := &Challenge{}
+	result := db.Preload("ChallengeQuestions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Limit(1).Find(c, "code = ?", strings.ToUpper(strings.TrimSpace(code)))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errChallengeNotFound
+	}
+	return c, nil
+}
+
+// newChallengeQuiz starts an attempt at a challenge, asking its questions with its settings
+func newChallengeQuiz(db *gorm.DB, user *website.User, code string, config QuizConfig) (Quiz, error) {
+	c, err := loadChallenge(db, code)
+	if err != nil {
+		return Quiz{}, err
+	}
+	questions := make([]OngoingQuizQuestion, 0, len(c.ChallengeQuestions))
+	for _, q := range c.ChallengeQuestions {
+		questions = append(questions, OngoingQuizQuestion{
+			Word:    q.Word,
+			Meaning: q.Meaning,
+			WordID:  q.WordID,
+			SenseID: q.SenseID,
+			Choices: q.Choices,
+		})
+	}
+	oq := OngoingQuiz{
+		Session:              uuid.NewString(),
+		OngoingQuizQuestions: questions,
+		UserID:               user.ID,
+		Direction:            c.Direction,
+		Format:               c.Format,
+		Tolerance:            c.Tolerance,
+		MaxDistance:          c.MaxDistance,
+		Feedback:             c.Feedback,
+		TimeLimit:            c.TimeLimit,
+		QuestionTimeLimit:    c.QuestionTimeLimit,
+		HintPenalty:          config.HintPenalty,
+		ChallengeCode:        c.Code,
+	}
+	if result := db.Create(&oq); result.Error != nil {
+		return Quiz{}, result.Error
+	}
+	return oq.quiz(), nil
+}
+
+// challengeResults lists every completed attempt at the challenge with how each question was answered
+func challengeResults(db *gorm.DB, code string) (*ChallengeResults, error) {
+	c, err := loadChallenge(db, code)
+	if err != nil {
+		return nil, err
+	}
+	results := &ChallengeResults{
+		Code:      c.Code,
+		Direction: c.Direction,
+		Format:    c.Format,
+		Questions: make([]ChallengeResultQuestion, 0, len(c.ChallengeQuestions)),
+		Attempts:  []ChallengeAttempt{},
+	}
+	positions := map[int]int{}
+	for i, q := range c.ChallengeQuestions {
+		prompt, expected := q.Word, q.Meaning
+		if c.Direction == directionReverse {
+			prompt, expected = q.Meaning, q.Word
+		}
+		results.Questions = append(results.Questions, ChallengeResultQuestion{Prompt: prompt, Expected: expected})
+		positions[q.Position] = i
+	}
+
+	attempts := []CompletedQuiz{}
+	result := db.Where("challenge_code = ? AND (abandoned IS NULL OR NOT abandoned)", c.Code).Order("taken_at").Find(&attempts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(attempts) == 0 {
+		return results, nil
+	}
+	sessions, userIDs := make([]string, 0, len(attempts)), make([]uint, 0, len(attempts))
+	for _, a := range attempts {
+		sessions = append(sessions, a.Session)
+		userIDs = append(userIDs, a.UserID)
+	}
+	users := []website.User{}
+	if result := db.Where("id IN ?", userIDs).Find(&users); result.Error != nil {
+		return nil, result.Error
+	}
+	usernames := map[uint]string{}
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+	answers := []CompletedQuizAnswer{}
+	if result := db.Where("session IN ?", sessions).Find(&answers); result.Error != nil {
+		return nil, result.Error
+	}
+	bySession := map[string][]CompletedQuizAnswer{}
+	for _, a := range answers {
+		bySession[a.Session] = append(bySession[a.Session], a)
+	}
+	for _, a := range attempts {
+		attempt := ChallengeAttempt{
+			Username:           usernames[a.UserID],
+			Session:            a.Session,
+			TakenAt:            a.TakenAt,
+			Score:              a.Score,
+			TotalQuestions:     a.TotalQuestions,
+			IncorrectQuestions: a.IncorrectQuestions,
+			SkippedQuestions:   a.SkippedQuestions,
+			DurationSeconds:    a.DurationSeconds,
+			Answers:            make([]ChallengeAnswer, len(results.Questions)),
+		}
+		for _, cqa := range bySession[a.Session] {
+			if i, ok := positions[cqa.Position]; ok {
+				attempt.Answers[i] = ChallengeAnswer{Chosen: cqa.Chosen, Outcome: cqa.Outcome}
+			}
+		}
+		results.Attempts = append(results.Attempts, attempt)
+	}
+	return results, nil
+}
+
+// Challenge is a quiz anyone can take with the same questions and choices, addressed by a short code
+type Challenge struct {
+	Code               string `gorm:"primaryKey"`
+	CreatedBy          uint
+	CreatedAt          time.Time
+	FromSession        string // the completed quiz it was made from, if any
+	Seed               int64  // the questions of a fresh challenge were picked with
+	Direction          string
+	Format             string
+	Tolerance          string
+	MaxDistance        int
+	Feedback           bool
+	TimeLimit          int
+	QuestionTimeLimit  int
+	ChallengeQuestions []ChallengeQuestion `gorm:"constraint:OnDelete:CASCADE;"`
+}
+
+type ChallengeQuestion struct {
+	ID            uint   `gorm:"primaryKey"`
+	ChallengeCode string `gorm:"index"`
+	Position      int
+	WordID        int
+	SenseID       uint // the sense of the word asked about; 0 from before senses
+	Word          string
+	Meaning       string
+	Choices       choiceList `gorm:"type:text"` // in the order offered
+}
+
+type ChallengeInfo struct {
+	Code      string
+	URL       string // to take the challenge
+	Direction string
+	Format    string
+	Questions int
+}
+
+type ChallengeResults struct {
+	Code      string
+	Direction string
+	Format    string
+	Questions []ChallengeResultQuestion
+	Attempts  []ChallengeAttempt // oldest first
+}
+
+type ChallengeResultQuestion struct {
+	Prompt   string
+	Expected string
+}
+
+type ChallengeAttempt struct {
+	Username           string
+	Session            string
+	TakenAt            time.Time
+	Score              float64
+	TotalQuestions     int
+	IncorrectQuestions int
+	SkippedQuestions   int
+	DurationSeconds    int
+	Answers            []ChallengeAnswer // in the order of ChallengeResults.Questions
+}
+
+type ChallengeAnswer struct {
+	Chosen  string
+	Outcome string
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/arunsworld/wordlist"
 	"github.com/arunsworld/wordlist/pkg/website"
@@ -16,6 +17,14 @@ var webContent embed.FS
 func main() {
 	port := flag.Int("port", 6123, "port to start app server on")
 	db := flag.String("db", "db.db", "location of db")
+	quizTTL := flag.Duration("quiz-ttl", 24*time.Hour, "unfinished quizzes untouched for longer than this are expired (0 to keep forever)")
+	recordAbandoned := flag.Bool("record-abandoned", true, "record expired quizzes as abandoned in scores")
+	choices := flag.Int("quiz-choices", 5, "choices offered per multiple choice question")
+	maxChoices := flag.Int("quiz-max-choices", 8, "most choices a quiz may ask for")
+	minQuestions := flag.Int("quiz-min", 1, "fewest questions a quiz may have")
+	maxQuestions := flag.Int("quiz-max", 50, "most questions a quiz may have")
+	distractorPool := flag.Int("distractor-pool", 0, "how many of the candidates closest to the answer distractors are drawn from (0 for three times as many as needed)")
+	hintPenalty := flag.Float64("hint-penalty", 0.25, "what a correct answer loses from its point for each hint used")
 	flag.Parse()
 
 	ws := website.NewWebsite(*db, "wordlist", webContent)
@@ -30,8 +39,18 @@ func main() {
 	setupHome(ws)
 	setupPingForKeepAlive(ws)
 	wordlist.SetupWordlist(ws)
-	wordlist.SetupQuiz(ws)
+	wordlist.SetupQuiz(ws, wordlist.QuizConfig{
+		AbandonAfter:    *quizTTL,
+		RecordAbandoned: *recordAbandoned,
+		Choices:         *choices,
+		MaxChoices:      *maxChoices,
+		MinQuestions:    *minQuestions,
+		MaxQuestions:    *maxQuestions,
+		DistractorPool:  *distractorPool,
+		HintPenalty:     *hintPenalty,
+	})
 	wordlist.SetupScores(ws)
+	wordlist.SetupWordLists(ws)
 
 	if err := ws.Serve(*port); err != nil {
 		ws.Close()
@@ -0,0 +1,247 @@
+package wordlist
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"gorm.io/gorm"
+)
+
+const (
+	distractorsMeanings = "meanings" // random choices of the same kind as the answer
+	distractorsMixed    = "mixed"    // random words and meanings, as quizzes originally had
+	distractorsSimilar  = "similar"  // choices of the answer's part of speech and a length similar to it
+	distractorsConfused = "confused" // choices the user has wrongly picked for the word before
+)
+
+// distractorStrategy picks the wrong choices offered alongside the answer to a question about the sense of w
+type distractorStrategy interface {
+	distractors(w Word, sense Sense, answer string, count int) []string
+}
+
+// distractorStrategy sets up the strategy the quiz asks for, making sure there are enough different
+// choices for questions with the given number of them
+func (qs quizSession) distractorStrategy(opts quizOptions, user *website.User, choices int) (distractorStrategy, error) {
+	// none of the word's other senses are wrong answers
+	pool, kind := qs.allMeanings, "meanings"
+	exclude := func(w Word, sense Sense) []string { return append([]string{w.Word}, w.meanings()...) }
+	if opts.Direction == directionReverse {
+		// nor are other words with the meaning asked about
+		wordsByMeaning := map[string][]string{}
+		for _, w := range qs.allWords {
+			for _, m := range w.meanings() {
+				wordsByMeaning[m] = append(wordsByMeaning[m], w.Word)
+			}
+		}
+		pool, kind = qs.allWordNames, "words"
+		exclude = func(w Word, sense Sense) []string { return wordsByMeaning[sense.Meaning] }
+	}
+	if opts.Distractors == distractorsMixed && opts.Direction != directionReverse {
+		pool, kind = qs.allMeaningsAndWords, "words and meanings"
+	}
+	if choices < 2 {
+		return nil, fmt.Errorf("%w: a multiple choice question needs at least 2 choices", errNotEnoughWords)
+	}
+	if n := distinct(pool); n < choices {
+		return nil, fmt.Errorf("%w: %d choices need as many different %s but there are only %d", errNotEnoughWords, choices, kind, n)
+	}
+	poolSize := opts.DistractorPool
+	if poolSize == 0 {
+		poolSize = qs.config.DistractorPool
+	}
+	similar := similarDistractors{qs: qs, pool: pool, exclude: exclude, poolSize: poolSize, partsOfSpeech: qs.partsOfSpeech()}
+	switch opts.Distractors {
+	case distractorsSimilar:
+		return similar, nil
+	case distractorsConfused:
+		confused, err := confusedChoices(qs.db, user.ID, opts.Direction)
+		if err != nil {
+			return nil, err
+		}
+		inPool := make(map[string]struct{}, len(pool))
+		for _, p := range pool {
+			inPool[p] = struct{}{}
+		}
+		return confusedDistractors{
+			confused: confused,
+			inPool:   inPool,
+			exclude:  exclude,
+			fallback: similar,
+		}, nil
+	default:
+		return randomDistractors{qs: qs, pool: pool, exclude: exclude}, nil
+	}
+}
+
+// choices offers the answer among count choices in random order
+func (qs quizSession) choices(strategy distractorStrategy, w Word, sense Sense, answer string, count int) []string {
+	result := append([]string{answer}, strategy.distractors(w, sense, answer, count-1)...)
+	qs.rnd.Shuffle(len(result), func(i, j int) {
+		result[i], result[j] = result[j], result[i]
+	})
+	return result
+}
+
+type randomDistractors struct {
+	qs      quizSession
+	pool    []string
+	exclude func(Word, Sense) []string
+}
+
+func (d randomDistractors) distractors(w Word, sense Sense, answer string, count int) []string {
+	return d.qs.randomChoices(d.pool, count, append(d.exclude(w, sense), answer)...)
+}
+
+type similarDistractors struct {
+	qs            quizSession
+	pool          []string
+	exclude       func(Word, Sense) []string
+	poolSize      int                 // how many of the closest candidates to draw from; 0 for three times as many as needed
+	partsOfSpeech map[string][]string // choice -> parts of speech of the senses it stands for
+}
+
+// partsOfSpeech maps each word and meaning to the parts of speech of the senses they belong to
+func (qs quizSession) partsOfSpeech() map[string][]string {
+	result := map[string][]string{}
+	add := func(choice, pos string) {
+		if pos != "" && !contains(result[choice], pos) {
+			result[choice] = append(result[choice], pos)
+		}
+	}
+	for _, w := range qs.allWords {
+		for _, s := range w.Senses {
+			add(w.Word, s.PartOfSpeech)
+			add(s.Meaning, s.PartOfSpeech)
+		}
+	}
+	return result
+}
+
+// distractors draws from the choices closest to the answer, first those of the same part of speech
+// as the sense asked about and then those closest in length, so the answer doesn't stand out
+func (d similarDistractors) distractors(w Word, sense Sense, answer string, count int) []string {
+	ignore := map[string]struct{}{answer: {}}
+	for _, e := range d.exclude(w, sense) {
+		ignore[e] = struct{}{}
+	}
+	candidates := make([]string, 0, len(d.pool))
+	for _, p := range d.pool {
+		if _, ok := ignore[p]; !ok {
+			candidates = append(candidates, p)
+			ignore[p] = struct{}{}
+		}
+	}
+	d.qs.rnd.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	target := len(answer)
+	samePOS := func(c string) bool {
+		return sense.PartOfSpeech != "" && contains(d.partsOfSpeech[c], sense.PartOfSpeech)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if pi, pj := samePOS(candidates[i]), samePOS(candidates[j]); pi != pj {
+			return pi
+		}
+		return abs(len(candidates[i])-target) < abs(len(candidates[j])-target)
+	})
+	// pick among more close candidates than needed so the same ones don't always come up
+	poolSize := d.poolSize
+	if poolSize == 0 {
+		poolSize = count * 3
+	}
+	if poolSize < count {
+		poolSize = count
+	}
+	if len(candidates) > poolSize {
+		candidates = candidates[:poolSize]
+	}
+	d.qs.rnd.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+	return candidates
+}
+
+type confusedDistractors struct {
+	confused map[int][]string // word ID -> choices wrongly picked, most often picked first
+	inPool   map[string]struct{}
+	exclude  func(Word, Sense) []string
+	fallback distractorStrategy
+}
+
+// distractors offers the choices the user was confused by again, topped up by the fallback strategy;
+// choices no longer in the pool, e.g. since edited meanings, are left out
+func (d confusedDistractors) distractors(w Word, sense Sense, answer string, count int) []string {
+	result := make([]string, 0, count)
+	excluded := d.exclude(w, sense)
+	for _, c := range d.confused[int(w.ID)] {
+		if len(result) == count {
+			return result
+		}
+		if _, ok := d.inPool[c]; ok && c != answer && !contains(excluded, c) {
+			result = append(result, c)
+		}
+	}
+	for _, f := range d.fallback.distractors(w, sense, answer, count) {
+		if len(result) == count {
+			break
+		}
+		if !contains(result, f) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// confusedChoices reads the wrong choices the user picked in multiple choice quizzes of the given direction
+func confusedChoices(db *gorm.DB, userID uint, direction string) (map[int][]string, error) {
+	type choice struct {
+		WordID int
+		Chosen string
+		Picks  int
+	}
+	choices := []choice{}
+	result := db.Table("incorrect_words").
+		Select("incorrect_words.word_id, incorrect_words.chosen, count(*) as picks").
+		Joins("join completed_quizzes on completed_quizzes.session = incorrect_words.session").
+		Where("completed_quizzes.user_id = ? AND completed_quizzes.direction = ? AND completed_quizzes.format = ?", userID, direction, formatChoice).
+		Where("incorrect_words.chosen IS NOT NULL AND incorrect_words.chosen != ''").
+		Group("incorrect_words.word_id, incorrect_words.chosen").
+		Order("picks desc").
+		Scan(&choices)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	confused := map[int][]string{}
+	for _, c := range choices {
+		confused[c.WordID] = append(confused[c.WordID], c.Chosen)
+	}
+	return confused, nil
+}
+
+func distinct(list []string) int {
+	seen := make(map[string]struct{}, len(list))
+	for _, l := range list {
+		seen[l] = struct{}{}
+	}
+	return len(seen)
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
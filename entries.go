@@ -0,0 +1,239 @@
+package wordlist
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	relationSynonym = "synonym"
+	relationAntonym = "antonym"
+)
+
+// WordExample is a sentence using the word; the first is mirrored in Word.Example
+type WordExample struct {
+	ID       uint `gorm:"primaryKey"`
+	WordID   uint `gorm:"index"`
+	Sentence string
+	Position int
+}
+
+// WordRelation is a synonym or antonym of a word, linked to its own entry when it's in the word list
+type WordRelation struct {
+	ID            uint   `gorm:"primaryKey"`
+	WordID        uint   `gorm:"index"`
+	Kind          string // relationSynonym or relationAntonym
+	Text          string
+	RelatedWordID *uint `gorm:"index"`
+}
+
+func migrateWordEntries(db *gorm.DB) {
+	moveExamples := !db.Migrator().HasTable(&WordExample{})
+	if err := db.AutoMigrate(&WordExample{}); err != nil {
+		panic(err)
+	}
+	if moveExamples {
+		if err := moveExamplesToTable(db); err != nil {
+			panic(err)
+		}
+	}
+	if err := db.AutoMigrate(&WordRelation{}); err != nil {
+		panic(err)
+	}
+}
+
+// moveExamplesToTable makes the example of each word from before there could be several its first
+func moveExamplesToTable(db *gorm.DB) error {
+	words := []Word{}
+	if result := db.Where("example IS NOT NULL AND example != ''").Find(&words); result.Error != nil {
+		return result.Error
+	}
+	examples := make([]WordExample, 0, len(words))
+	for _, w := range words {
+		examples = append(examples, WordExample{WordID: w.ID, Sentence: w.Example})
+	}
+	if len(examples) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(examples, 100).Error
+}
+
+// wordEntryForm is what the add and save endpoints were sent for examples, synonyms and antonyms;
+// a nil list wasn't sent and is left alone
+type wordEntryForm struct {
+	examples []string
+	synonyms []string
+	antonyms []string
+}
+
+// parseWordEntryForm reads repeated or newline separated "example" fields and comma separated
+// "synonyms" and "antonyms"
+func parseWordEntryForm(form map[string][]string) wordEntryForm {
+	entry := wordEntryForm{}
+	if values, ok := form["example"]; ok {
+		entry.examples = []string{}
+		for _, v := range values {
+			for _, sentence := range strings.Split(v, "\n") {
+				if sentence = strings.TrimSpace(sentence); sentence != "" && !contains(entry.examples, sentence) {
+					entry.examples = append(entry.examples, sentence)
+				}
+			}
+		}
+	}
+	if values, ok := form["synonyms"]; ok {
+		entry.synonyms = relatedWords(values)
+	}
+	if values, ok := form["antonyms"]; ok {
+		entry.antonyms = relatedWords(values)
+	}
+	return entry
+}
+
+func relatedWords(values []string) []string {
+	words := []string{}
+	for _, v := range values {
+		for _, w := range strings.Split(v, ",") {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" && !contains(words, w) {
+				words = append(words, w)
+			}
+		}
+	}
+	return words
+}
+
+// save replaces the word's examples and relations with those sent, or adds them to what it has
+func (entry wordEntryForm) save(tx *gorm.DB, wordID uint, replace bool) error {
+	if entry.examples != nil {
+		if err := saveExamples(tx, wordID, entry.examples, replace); err != nil {
+			return err
+		}
+	}
+	if entry.synonyms != nil {
+		if err := saveRelations(tx, wordID, relationSynonym, entry.synonyms, replace); err != nil {
+			return err
+		}
+	}
+	if entry.antonyms != nil {
+		if err := saveRelations(tx, wordID, relationAntonym, entry.antonyms, replace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveExamples(tx *gorm.DB, wordID uint, sentences []string, replace bool) error {
+	existing := []WordExample{}
+	if replace {
+		if err := tx.Where("word_id = ?", wordID).Delete(&WordExample{}).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Where("word_id = ?", wordID).Order("position").Find(&existing).Error; err != nil {
+		return err
+	}
+	have := make([]string, 0, len(existing))
+	for _, e := range existing {
+		have = append(have, e.Sentence)
+	}
+	for _, s := range sentences {
+		if contains(have, s) {
+			continue
+		}
+		if err := tx.Create(&WordExample{WordID: wordID, Sentence: s, Position: len(have)}).Error; err != nil {
+			return err
+		}
+		have = append(have, s)
+	}
+	first := ""
+	if len(have) > 0 {
+		first = have[0]
+	}
+	return tx.Model(&Word{ID: wordID}).Update("example", first).Error
+}
+
+func saveRelations(tx *gorm.DB, wordID uint, kind string, words []string, replace bool) error {
+	existing := []WordRelation{}
+	if replace {
+		if err := tx.Where("word_id = ? AND kind = ?", wordID, kind).Delete(&WordRelation{}).Error; err != nil {
+			return err
+		}
+	} else if err := tx.Where("word_id = ? AND kind = ?", wordID, kind).Find(&existing).Error; err != nil {
+		return err
+	}
+	have := make([]string, 0, len(existing))
+	for _, r := range existing {
+		have = append(have, r.Text)
+	}
+	related := []Word{}
+	if err := tx.Select("id, word").Where("word IN ?", words).Find(&related).Error; err != nil {
+		return err
+	}
+	ids := make(map[string]uint, len(related))
+	for _, w := range related {
+		ids[w.Word] = w.ID
+	}
+	for _, w := range words {
+		if contains(have, w) {
+			continue
+		}
+		relation := &WordRelation{WordID: wordID, Kind: kind, Text: w}
+		if id, ok := ids[w]; ok && id != wordID {
+			relation.RelatedWordID = &id
+		}
+		if err := tx.Create(relation).Error; err != nil {
+			return err
+		}
+		have = append(have, w)
+	}
+	return nil
+}
+
+// linkRelations points synonyms and antonyms given before the word was added at its new entry,
+// returning the IDs of the words they belong to
+func linkRelations(tx *gorm.DB, w *Word) ([]uint, error) {
+	unlinked := func() *gorm.DB {
+		return tx.Model(&WordRelation{}).Where("text = ? AND related_word_id IS NULL AND word_id != ?", w.Word, w.ID)
+	}
+	linked := []uint{}
+	if err := unlinked().Distinct("word_id").Pluck("word_id", &linked).Error; err != nil {
+		return nil, err
+	}
+	if len(linked) == 0 {
+		return linked, nil
+	}
+	return linked, unlinked().Update("related_word_id", w.ID).Error
+}
+
+// related lists the text of the word's relations of the given kind
+func (w Word) related(kind string) []string {
+	related := []string{}
+	for _, r := range w.Relations {
+		if r.Kind == kind {
+			related = append(related, r.Text)
+		}
+	}
+	return related
+}
+
+// partOfSpeech of the sense asked about, or of the word's primary sense
+func (w Word) partOfSpeech(senseID uint) string {
+	for _, s := range w.Senses {
+		if s.ID == senseID {
+			return s.PartOfSpeech
+		}
+	}
+	if len(w.Senses) > 0 {
+		return w.Senses[0].PartOfSpeech
+	}
+	return ""
+}
+
+// describe adds what the word list has on the missed word, to learn it from
+func (ia *IncorrectAnswer) describe(w Word, senseID uint) {
+	ia.PartOfSpeech = w.partOfSpeech(senseID)
+	for _, e := range w.Examples {
+		ia.Examples = append(ia.Examples, e.Sentence)
+	}
+	ia.Synonyms = w.related(relationSynonym)
+	ia.Antonyms = w.related(relationAntonym)
+}
@@ -0,0 +1,118 @@
+package wordlist
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	outcomeCorrect    = "correct"
+	outcomeMisspelled = "misspelled"
+	outcomeIncorrect  = "incorrect"
+	outcomeSkipped    = "skipped" // "I don't know", or no answer
+)
+
+const (
+	toleranceExact       = "exact"       // must match exactly
+	toleranceInsensitive = "insensitive" // case and diacritics are ignored
+	toleranceFuzzy       = "fuzzy"       // as insensitive, and within N edits is misspelled rather than wrong
+)
+
+// gradeTyped grades a typed answer against the expected word
+func gradeTyped(answer, expected, tolerance string, maxDistance int) string {
+	answer = strings.TrimSpace(answer)
+	expected = strings.TrimSpace(expected)
+	if answer == expected {
+		return outcomeCorrect
+	}
+	if tolerance == toleranceExact || answer == "" {
+		return outcomeIncorrect
+	}
+	a, e := normalizeAnswer(answer), normalizeAnswer(expected)
+	if a == e {
+		return outcomeCorrect
+	}
+	if tolerance == toleranceFuzzy && levenshtein(a, e) <= maxDistance {
+		return outcomeMisspelled
+	}
+	return outcomeIncorrect
+}
+
+func normalizeAnswer(s string) string {
+	return strings.Join(strings.Fields(foldDiacritics(strings.ToLower(s))), " ")
+}
+
+var diacriticFolds = map[string]string{
+	"a": "àáâãäåāăą",
+	"c": "çćĉċč",
+	"d": "ďđ",
+	"e": "èéêëēĕėęě",
+	"g": "ĝğġģ",
+	"h": "ĥħ",
+	"i": "ìíîïĩīĭįı",
+	"j": "ĵ",
+	"k": "ķ",
+	"l": "ĺļľŀł",
+	"n": "ñńņňŉ",
+	"o": "òóôõöøōŏő",
+	"r": "ŕŗř",
+	"s": "śŝşš",
+	"t": "ţťŧ",
+	"u": "ùúûüũūŭůűų",
+	"w": "ŵ",
+	"y": "ýÿŷ",
+	"z": "źżž",
+}
+
+var diacriticReplacer = func() *strings.Replacer {
+	pairs := []string{"æ", "ae", "œ", "oe", "ß", "ss"}
+	for plain, accented := range diacriticFolds {
+		for _, r := range accented {
+			pairs = append(pairs, string(r), plain)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+// foldDiacritics maps accented latin letters to their plain form; expects lower case input
+func foldDiacritics(s string) string {
+	s = diacriticReplacer.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	m := a
+	if b < m {
+		m = b
+	}
+	if c < m {
+		m = c
+	}
+	return m
+}
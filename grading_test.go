@@ -0,0 +1,66 @@
+package wordlist
+
+import "testing"
+
+func TestGradeTyped(t *testing.T) {
+	tests := []struct {
+		answer, expected, tolerance string
+		maxDistance                 int
+		want                        string
+	}{
+		{"doux", "doux", toleranceExact, 0, outcomeCorrect},
+		{"  doux ", "doux", toleranceExact, 0, outcomeCorrect},
+		{"Doux", "doux", toleranceExact, 0, outcomeIncorrect},
+		{"Doux", "doux", toleranceInsensitive, 0, outcomeCorrect},
+		{"naive", "naïve", toleranceInsensitive, 0, outcomeCorrect},
+		{"cafe  au lait", "café au lait", toleranceInsensitive, 0, outcomeCorrect},
+		{"niave", "naïve", toleranceInsensitive, 0, outcomeIncorrect},
+		{"niave", "naïve", toleranceFuzzy, 2, outcomeMisspelled},
+		{"nave", "naïve", toleranceFuzzy, 1, outcomeMisspelled},
+		{"xyz", "naïve", toleranceFuzzy, 2, outcomeIncorrect},
+		{"", "naïve", toleranceFuzzy, 10, outcomeIncorrect},
+	}
+	for _, tt := range tests {
+		if got := gradeTyped(tt.answer, tt.expected, tt.tolerance, tt.maxDistance); got != tt.want {
+			t.Errorf("gradeTyped(%q, %q, %s, %d) = %s, want %s", tt.answer, tt.expected, tt.tolerance, tt.maxDistance, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"same", "same", 0},
+		{"naïve", "naive", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFoldDiacritics(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"naïve", "naive"},
+		{"café", "cafe"},
+		{"façade", "facade"},
+		{"straße", "strasse"},
+		{"encyclopædia", "encyclopaedia"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := foldDiacritics(tt.in); got != tt.want {
+			t.Errorf("foldDiacritics(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
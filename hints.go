@@ -0,0 +1,150 @@
+package wordlist
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"gorm.io/gorm"
+)
+
+const (
+	hintEliminate   = "eliminate"    // two wrong choices are taken away
+	hintFirstLetter = "first_letter" // the first letter of the answer
+	hintExample     = "example"      // an example sentence using the word
+)
+
+var errHintUnavailable = errors.New("hint is not available")
+
+// takeHint gives a hint for a question of an ongoing quiz and records that it was used; asking for
+// a hint already given repeats it without it counting again
+func takeHint(db *gorm.DB, user *website.User, req HintRequest) (*Hint, error) {
+	oq, err := loadOngoingQuiz(db, user, req.Session)
+	if err != nil {
+		return nil, err
+	}
+	oqq, err := oq.question(req.QuestionID)
+	if err != nil {
+		return nil, err
+	}
+	if oq.Feedback && oqq.Answered {
+		return nil, errAnswerLocked
+	}
+	// no hints once the quiz or question is out of time, as no answer would count
+	if !oq.inTime(oqq, time.Now()) {
+		return nil, errTimeUp
+	}
+	if contains(oqq.Hints, req.Kind) {
+		return oqq.hint(), nil
+	}
+	updates := map[string]interface{}{}
+	expected := oqq.expected(oq.Direction)
+	switch req.Kind {
+	case hintEliminate:
+		wrong := []string{}
+		for _, c := range oqq.Choices {
+			if c != expected {
+				wrong = append(wrong, c)
+			}
+		}
+		// at least one wrong choice stays, or the hint would give the answer away
+		eliminate := len(wrong) - 1
+		if eliminate > 2 {
+			eliminate = 2
+		}
+		if eliminate < 1 {
+			return nil, fmt.Errorf("%w: too few choices to take any away", errHintUnavailable)
+		}
+		rand.Shuffle(len(wrong), func(i, j int) {
+			wrong[i], wrong[j] = wrong[j], wrong[i]
+		})
+		oqq.Eliminated = wrong[:eliminate]
+		updates["eliminated"] = oqq.Eliminated
+	case hintFirstLetter:
+		r, _ := utf8.DecodeRuneInString(expected)
+		oqq.FirstLetter = string(r)
+		updates["first_letter"] = oqq.FirstLetter
+	case hintExample:
+		found, err := wordIndex.byIDs(db, []int{oqq.WordID})
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 || found[0].Example == "" {
+			return nil, fmt.Errorf("%w: there is no example for this word", errHintUnavailable)
+		}
+		oqq.Example = found[0].Example
+		if oq.Direction == directionReverse {
+			// the example mustn't give away the word being asked for
+			oqq.Example = regexp.MustCompile("(?i)"+regexp.QuoteMeta(oqq.Word)).ReplaceAllString(oqq.Example, "____")
+		}
+		updates["example"] = oqq.Example
+	default:
+		return nil, fmt.Errorf("%w: unknown hint %q", errHintUnavailable, req.Kind)
+	}
+	oqq.Hints = append(oqq.Hints, req.Kind)
+	updates["hints"] = oqq.Hints
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(oqq).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Model(oq).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return oqq.hint(), nil
+}
+
+// hint is what the learner has been given so far for the question, if anything
+func (oqq OngoingQuizQuestion) hint() *Hint {
+	if len(oqq.Hints) == 0 {
+		return nil
+	}
+	return &Hint{
+		Kinds:       oqq.Hints,
+		Eliminated:  oqq.Eliminated,
+		FirstLetter: oqq.FirstLetter,
+		Example:     oqq.Example,
+	}
+}
+
+// hintedScore is what a correct answer is worth after the penalty for the hints used
+func hintedScore(hints int, penalty float64) float64 {
+	score := 1 - float64(hints)*penalty
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// backfillScores scores quizzes taken before hints existed, when every correct answer was worth a point
+func backfillScores(db *gorm.DB) error {
+	result := db.Model(&CompletedQuiz{}).Where("abandoned IS NULL OR NOT abandoned").
+		Update("score", gorm.Expr("total_questions - incorrect_questions"))
+	if result.Error != nil {
+		return result.Error
+	}
+	return db.Model(&CompletedQuizAnswer{}).Where("correct").Update("score", 1).Error
+}
+
+type HintRequest struct {
+	Session    string
+	QuestionID uint
+	Kind       string
+}
+
+type Hint struct {
+	Kinds       []string // hints used, in the order asked for
+	Eliminated  []string `json:",omitempty"`
+	FirstLetter string   `json:",omitempty"`
+	Example     string   `json:",omitempty"`
+}
+
+type HintedWord struct {
+	Word  string
+	Hints []string
+}
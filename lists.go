@@ -0,0 +1,348 @@
+package wordlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	visibilityPrivate = "private" // only its owner sees it
+	visibilityShared  = "shared"  // anyone with its link can see and quiz it
+	visibilityPublic  = "public"  // listed for everyone as well
+)
+
+var (
+	errListNotFound = errors.New("word list not found")
+	errInvalidList  = errors.New("invalid word list")
+)
+
+// WordList is a user's own list of words to study, drawn from the word list
+type WordList struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"uniqueIndex:idx_word_list_owner_name"`
+	OwnerID    uint   `gorm:"uniqueIndex:idx_word_list_owner_name"`
+	Visibility string `gorm:"default:private"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Words      []Word `gorm:"many2many:word_list_words"`
+}
+
+func SetupWordLists(ws *website.Website) {
+	if err := ws.DB().AutoMigrate(&WordList{}); err != nil {
+		panic(err)
+	}
+
+	listsHTML, err := ws.WebsiteContent().ReadFile("web/html/lists.html")
+	if err != nil {
+		panic(err)
+	}
+
+	lists := ws.Router().Path("/lists/").Methods("GET").Subrouter()
+	lists.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{}))
+	lists.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(listsHTML)
+	})
+
+	listsAPI := ws.Router().PathPrefix("/lists-api/").Methods("GET").Subrouter()
+	listsAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{IsForAPI: true}))
+	listsAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		summaries, err := wordListSummaries(ws.DB(), ws.AuthenticatedUser(r))
+		if err != nil {
+			log.Printf("error reading word lists from DB: %v", err)
+			http.Error(w, "unable to read word lists", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(summaries); err != nil {
+			log.Printf("error encoding word lists: %v", err)
+			http.Error(w, "unable to read word lists", http.StatusInternalServerError)
+			return
+		}
+	})
+	// the words there are to put on lists
+	listsAPI.HandleFunc("/words/", func(w http.ResponseWriter, r *http.Request) {
+		allWords, err := wordIndex.all(ws.DB())
+		if err != nil {
+			log.Printf("error reading words from DB: %v", err)
+			http.Error(w, "unable to read words", http.StatusInternalServerError)
+			return
+		}
+		entries := make([]ListWord, 0, len(allWords))
+		for _, wrd := range allWords {
+			entries = append(entries, ListWord{ID: wrd.ID, Word: wrd.Word, Meaning: wrd.Meaning})
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			log.Printf("error encoding words: %v", err)
+			http.Error(w, "unable to read words", http.StatusInternalServerError)
+			return
+		}
+	})
+	listsAPI.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		list, err := visibleWordList(ws.DB(), user, mux.Vars(r)["id"])
+		if err != nil {
+			listSaved(ws.DB(), w, user, nil, err)
+			return
+		}
+		writeWordList(ws.DB(), w, user, list)
+	})
+
+	listsPOSTAPI := ws.Router().PathPrefix("/lists-api/").Methods("POST").Subrouter()
+	listsPOSTAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{IsForAPI: true}))
+	listsPOSTAPI.HandleFunc("/new/", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		list := &WordList{OwnerID: user.ID, Visibility: visibilityPrivate}
+		err := list.update(ws.DB(), r.FormValue("name"), r.FormValue("visibility"))
+		if err == nil {
+			err = ws.DB().Create(list).Error
+		}
+		listSaved(ws.DB(), w, user, list, err)
+	})
+	listsPOSTAPI.HandleFunc("/{id}/save/", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		list, err := ownWordList(ws.DB(), user, mux.Vars(r)["id"])
+		if err == nil {
+			err = list.update(ws.DB(), r.FormValue("name"), r.FormValue("visibility"))
+		}
+		if err == nil {
+			err = ws.DB().Model(list).Updates(map[string]interface{}{"name": list.Name, "visibility": list.Visibility}).Error
+		}
+		listSaved(ws.DB(), w, user, list, err)
+	})
+	listsPOSTAPI.HandleFunc("/{id}/delete/", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		list, err := ownWordList(ws.DB(), user, mux.Vars(r)["id"])
+		if err == nil {
+			err = ws.DB().Select("Words").Delete(list).Error
+		}
+		if err != nil {
+			listSaved(ws.DB(), w, user, nil, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte("{}"))
+	})
+	// adding and removing take the word IDs as a comma separated "wids" list
+	listsPOSTAPI.HandleFunc("/{id}/add/", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		list, words, err := listAndWords(ws.DB(), user, mux.Vars(r)["id"], r.FormValue("wids"))
+		if err == nil {
+			err = ws.DB().Model(list).Omit("Words.*").Association("Words").Append(words)
+		}
+		listSaved(ws.DB(), w, user, list, err)
+	})
+	listsPOSTAPI.HandleFunc("/{id}/remove/", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		list, words, err := listAndWords(ws.DB(), user, mux.Vars(r)["id"], r.FormValue("wids"))
+		if err == nil {
+			err = ws.DB().Model(list).Association("Words").Delete(words)
+		}
+		listSaved(ws.DB(), w, user, list, err)
+	})
+}
+
+// update sets the name and visibility sent, keeping those it has for anything not sent
+func (list *WordList) update(db *gorm.DB, name, visibility string) error {
+	if name = strings.TrimSpace(name); name != "" {
+		taken := db.Limit(1).Find(&WordList{}, "owner_id = ? AND name = ? AND id != ?", list.OwnerID, name, list.ID)
+		if taken.Error != nil {
+			return taken.Error
+		}
+		if taken.RowsAffected > 0 {
+			return fmt.Errorf("%w: you already have a list called %s", errInvalidList, name)
+		}
+		list.Name = name
+	}
+	if list.Name == "" {
+		return fmt.Errorf("%w: a list needs a name", errInvalidList)
+	}
+	switch visibility {
+	case "":
+	case visibilityPrivate, visibilityShared, visibilityPublic:
+		list.Visibility = visibility
+	default:
+		return fmt.Errorf("%w: unknown visibility %s", errInvalidList, visibility)
+	}
+	return nil
+}
+
+func findWordList(db *gorm.DB, idStr string) (*WordList, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, errListNotFound
+	}
+	list := &WordList{}
+	result := db.Preload("Words", func(db *gorm.DB) *gorm.DB {
+		return db.Order("word")
+	}).Limit(1).Find(list, id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errListNotFound
+	}
+	return list, nil
+}
+
+// visibleWordList is a list the user may see and quiz: their own, or another's that isn't private
+func visibleWordList(db *gorm.DB, user *website.User, idStr string) (*WordList, error) {
+	list, err := findWordList(db, idStr)
+	if err != nil {
+		return nil, err
+	}
+	if list.OwnerID != user.ID && list.Visibility == visibilityPrivate {
+		return nil, errListNotFound
+	}
+	return list, nil
+}
+
+// ownWordList is a list the user may change
+func ownWordList(db *gorm.DB, user *website.User, idStr string) (*WordList, error) {
+	list, err := findWordList(db, idStr)
+	if err != nil {
+		return nil, err
+	}
+	if list.OwnerID != user.ID {
+		return nil, errListNotFound
+	}
+	return list, nil
+}
+
+func listAndWords(db *gorm.DB, user *website.User, idStr, widsStr string) (*WordList, []Word, error) {
+	list, err := ownWordList(db, user, idStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := []int{}
+	for _, s := range strings.Split(widsStr, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || id < 1 {
+			return nil, nil, fmt.Errorf("%w: wids must be a comma separated list of word IDs", errInvalidList)
+		}
+		ids = append(ids, id)
+	}
+	words, err := wordIndex.byIDs(db, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(words) == 0 {
+		return nil, nil, fmt.Errorf("%w: none of the words exist", errInvalidList)
+	}
+	// only the link to the list is wanted; the words are as they were
+	for i := range words {
+		words[i] = Word{ID: words[i].ID, Word: words[i].Word, Meaning: words[i].Meaning}
+	}
+	return list, words, nil
+}
+
+// listWordIDs are the words of a list the user may quiz
+func listWordIDs(db *gorm.DB, user *website.User, id int) ([]int, error) {
+	list, err := visibleWordList(db, user, strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(list.Words))
+	for _, w := range list.Words {
+		ids = append(ids, int(w.ID))
+	}
+	return ids, nil
+}
+
+// listSaved reports how a change to a list went, with the list as it is now
+func listSaved(db *gorm.DB, w http.ResponseWriter, user *website.User, list *WordList, err error) {
+	switch {
+	case errors.Is(err, errListNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errInvalidList):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("error saving word list: %v", err)
+		http.Error(w, "unable to save word list", http.StatusInternalServerError)
+		return
+	}
+	list, err = findWordList(db, strconv.Itoa(int(list.ID)))
+	if err != nil {
+		log.Printf("error reading word list from DB: %v", err)
+		http.Error(w, "unable to read word list", http.StatusInternalServerError)
+		return
+	}
+	writeWordList(db, w, user, list)
+}
+
+func writeWordList(db *gorm.DB, w http.ResponseWriter, user *website.User, list *WordList) {
+	owner := website.User{}
+	if result := db.Limit(1).Find(&owner, list.OwnerID); result.Error != nil {
+		log.Printf("error reading word list owner from DB: %v", result.Error)
+		http.Error(w, "unable to read word list", http.StatusInternalServerError)
+		return
+	}
+	details := WordListDetails{
+		WordListSummary: WordListSummary{
+			ID:         list.ID,
+			Name:       list.Name,
+			Owner:      owner.Username,
+			Mine:       list.OwnerID == user.ID,
+			Visibility: list.Visibility,
+			Words:      len(list.Words),
+		},
+		Entries: make([]ListWord, 0, len(list.Words)),
+	}
+	for _, wrd := range list.Words {
+		details.Entries = append(details.Entries, ListWord{ID: wrd.ID, Word: wrd.Word, Meaning: wrd.Meaning})
+	}
+	w.Header().Set("Content-Type", "application/javascript")
+	if err := json.NewEncoder(w).Encode(details); err != nil {
+		log.Printf("error encoding word list: %v", err)
+		http.Error(w, "unable to read word list", http.StatusInternalServerError)
+		return
+	}
+}
+
+// wordListSummaries are the user's own lists followed by everyone else's public ones
+func wordListSummaries(db *gorm.DB, user *website.User) ([]WordListSummary, error) {
+	summaries := []WordListSummary{}
+	result := db.Model(&WordList{}).
+		Select("word_lists.id, word_lists.name, users.username as owner, word_lists.owner_id = ? as mine, "+
+			"word_lists.visibility, count(word_list_words.word_id) as words", user.ID).
+		Joins("left join users on users.id = word_lists.owner_id").
+		Joins("left join word_list_words on word_list_words.word_list_id = word_lists.id").
+		Where("word_lists.owner_id = ? OR word_lists.visibility = ?", user.ID, visibilityPublic).
+		Group("word_lists.id, word_lists.name, users.username, word_lists.owner_id, word_lists.visibility").
+		Order("mine desc, word_lists.name").
+		Scan(&summaries)
+	return summaries, result.Error
+}
+
+type WordListSummary struct {
+	ID         uint
+	Name       string
+	Owner      string
+	Mine       bool
+	Visibility string
+	Words      int
+}
+
+type WordListDetails struct {
+	WordListSummary
+	Entries []ListWord
+}
+
+type ListWord struct {
+	ID      uint
+	Word    string
+	Meaning string
+}
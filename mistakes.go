@@ -0,0 +1,107 @@
+package wordlist
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var errNoMistakes = errors.New("no missed words to review")
+
+type miss struct {
+	WordID   int
+	Misses   int
+	LastMiss string
+}
+
+// missedWords counts the misses of each word the user still gets wrong or doesn't know: words
+// answered correctly after their last miss are left out
+func missedWords(db *gorm.DB, userID uint, opts quizOptions, now time.Time) (map[int]int, error) {
+	misses := []miss{}
+	for _, table := range []string{"incorrect_words", "skipped_words"} {
+		m, err := missesIn(db, table, userID, opts, now)
+		if err != nil {
+			return nil, err
+		}
+		misses = append(misses, m...)
+	}
+
+	type correct struct {
+		WordID      int
+		LastCorrect string
+	}
+	corrects := []correct{}
+	result := db.Table("completed_quiz_answers").
+		Select("completed_quiz_answers.word_id, max(completed_quizzes.taken_at) as last_correct").
+		Joins("join completed_quizzes on completed_quizzes.session = completed_quiz_answers.session").
+		Where("completed_quizzes.user_id = ? AND completed_quiz_answers.correct", userID).
+		Group("completed_quiz_answers.word_id").
+		Scan(&corrects)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	lastCorrect := make(map[int]time.Time, len(corrects))
+	for _, c := range corrects {
+		t, err := parseSQLiteTime(c.LastCorrect)
+		if err != nil {
+			return nil, err
+		}
+		lastCorrect[c.WordID] = t
+	}
+
+	missed := make(map[int]int, len(misses))
+	lastMisses := make(map[int]time.Time, len(misses))
+	for _, m := range misses {
+		lastMiss, err := parseSQLiteTime(m.LastMiss)
+		if err != nil {
+			return nil, err
+		}
+		missed[m.WordID] += m.Misses
+		if lastMiss.After(lastMisses[m.WordID]) {
+			lastMisses[m.WordID] = lastMiss
+		}
+	}
+	for wordID, lastMiss := range lastMisses {
+		if t, ok := lastCorrect[wordID]; ok && t.After(lastMiss) {
+			delete(missed, wordID)
+		}
+	}
+	return missed, nil
+}
+
+// missesIn counts the misses of each word recorded in table, incorrect_words or skipped_words
+func missesIn(db *gorm.DB, table string, userID uint, opts quizOptions, now time.Time) ([]miss, error) {
+	query := db.Table(table).
+		Select(table+".word_id, count(*) as misses, max(completed_quizzes.taken_at) as last_miss").
+		Joins("join completed_quizzes on completed_quizzes.session = "+table+".session").
+		Where("completed_quizzes.user_id = ?", userID)
+	if opts.MistakesDays > 0 {
+		query = query.Where("completed_quizzes.taken_at >= ?", now.AddDate(0, 0, -opts.MistakesDays))
+	}
+	if opts.MistakesQuizzes > 0 {
+		recent := db.Model(&CompletedQuiz{}).Select("session").
+			Where("user_id = ? AND (abandoned IS NULL OR NOT abandoned)", userID).
+			Order("taken_at desc").Limit(opts.MistakesQuizzes)
+		query = query.Where("completed_quizzes.session IN (?)", recent)
+	}
+	misses := []miss{}
+	result := query.Group(table + ".word_id").Scan(&misses)
+	return misses, result.Error
+}
+
+// weightedShuffle orders words randomly, with heavier words more likely to come first
+// (Efraimidis-Spirakis sampling without replacement)
+func weightedShuffle(rnd *rand.Rand, words []Word, weight func(Word) int) []Word {
+	keys := make(map[uint]float64, len(words))
+	for _, w := range words {
+		keys[w.ID] = math.Pow(rnd.Float64(), 1/float64(weight(w)))
+	}
+	sort.Slice(words, func(i, j int) bool {
+		return keys[words[i].ID] > keys[words[j].ID]
+	})
+	return words
+}
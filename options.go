@@ -0,0 +1,236 @@
+package wordlist
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	sourceAll      = "all"
+	sourceDue      = "due"      // words due for spaced-repetition review
+	sourceMistakes = "mistakes" // words the user has missed and not answered correctly since
+	sourceList     = "list"     // the words of one of the word lists users keep
+
+	sourceAssignment = "assignment" // the words of an assignment; not for choosing in the query
+)
+
+const (
+	directionForward = "forward" // word -> meaning
+	directionReverse = "reverse" // meaning -> word
+)
+
+const (
+	formatChoice = "choice" // multiple choice
+	formatTyped  = "typed"  // the word is typed in response to the meaning
+)
+
+const (
+	defaultMaxDistance = 1
+	maxMaxDistance     = 5
+)
+
+type quizOptions struct {
+	Source         string
+	Direction      string
+	Format         string
+	Tolerance      string
+	MaxDistance    int
+	Feedback       bool   // each answer is graded as it is made
+	Distractors    string // how wrong choices are picked for multiple choice quizzes
+	Choices        int    // choices per multiple choice question; 0 for the configured default
+	DistractorPool int    // see QuizConfig.DistractorPool; 0 for the configured default
+
+	TimeLimit         int // seconds for the whole quiz; 0 for no limit
+	QuestionTimeLimit int // seconds for each question; 0 for no limit
+
+	MistakesDays    int // only mistakes made within this many days
+	MistakesQuizzes int // only mistakes made within this many most recent quizzes
+
+	ListID int // the word list to quiz
+
+	Tags []string // only words carrying any of these tags
+
+	Challenge  string // code of a challenge to take; its own settings replace all of the above
+	Assignment int    // ID of an assignment to take; likewise
+
+	words []Word // of the assignment being taken
+}
+
+func parseQuizOptions(v url.Values, config QuizConfig) (quizOptions, error) {
+	opts := quizOptions{
+		Source:    v.Get("source"),
+		Direction: v.Get("direction"),
+		Format:    v.Get("format"),
+		Tolerance: v.Get("tolerance"),
+		Challenge: strings.ToUpper(strings.TrimSpace(v.Get("challenge"))),
+		Tags:      parseTags(v.Get("tags")),
+	}
+	if f := v.Get("feedback"); f != "" {
+		feedback, err := strconv.ParseBool(f)
+		if err != nil {
+			return opts, fmt.Errorf("feedback must be true or false")
+		}
+		opts.Feedback = feedback
+	}
+	var err error
+	if opts.TimeLimit, err = optionalPositiveInt(v, "limit"); err != nil {
+		return opts, err
+	}
+	if opts.QuestionTimeLimit, err = optionalPositiveInt(v, "question_limit"); err != nil {
+		return opts, err
+	}
+	if opts.Assignment, err = optionalPositiveInt(v, "assignment"); err != nil {
+		return opts, err
+	}
+	switch opts.Source {
+	case "":
+		opts.Source = sourceAll
+	case sourceAll, sourceDue:
+	case sourceMistakes:
+		if opts.MistakesDays, err = optionalPositiveInt(v, "days"); err != nil {
+			return opts, err
+		}
+		if opts.MistakesQuizzes, err = optionalPositiveInt(v, "quizzes"); err != nil {
+			return opts, err
+		}
+	case sourceList:
+		if opts.ListID, err = optionalPositiveInt(v, "list"); err != nil {
+			return opts, err
+		}
+		if opts.ListID == 0 {
+			return opts, fmt.Errorf("a list quiz needs the list to quiz")
+		}
+	default:
+		return opts, fmt.Errorf("unknown quiz source: %s", opts.Source)
+	}
+	switch opts.Direction {
+	case "":
+		opts.Direction = directionForward
+	case directionForward, directionReverse:
+	default:
+		return opts, fmt.Errorf("unknown quiz direction: %s", opts.Direction)
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = formatChoice
+	case formatChoice:
+	case formatTyped:
+		if v.Get("direction") == directionForward {
+			return opts, fmt.Errorf("typed quizzes prompt with the meaning and cannot be forward")
+		}
+		opts.Direction = directionReverse
+	default:
+		return opts, fmt.Errorf("unknown quiz format: %s", opts.Format)
+	}
+	if opts.Format != formatTyped {
+		opts.Tolerance = ""
+		switch opts.Distractors = v.Get("distractors"); opts.Distractors {
+		case "":
+			opts.Distractors = distractorsMeanings
+		case distractorsMeanings, distractorsMixed, distractorsSimilar, distractorsConfused:
+		default:
+			return opts, fmt.Errorf("unknown distractor strategy: %s", opts.Distractors)
+		}
+		if c := v.Get("choices"); c != "" {
+			choices, err := strconv.Atoi(c)
+			if err != nil || choices < 2 || choices > config.MaxChoices {
+				return opts, fmt.Errorf("choices must be between 2 and %d", config.MaxChoices)
+			}
+			opts.Choices = choices
+		}
+		if opts.DistractorPool, err = optionalPositiveInt(v, "pool"); err != nil {
+			return opts, err
+		}
+		return opts, nil
+	}
+	switch opts.Tolerance {
+	case "":
+		opts.Tolerance = toleranceFuzzy
+	case toleranceExact, toleranceInsensitive, toleranceFuzzy:
+	default:
+		return opts, fmt.Errorf("unknown answer tolerance: %s", opts.Tolerance)
+	}
+	if opts.Tolerance == toleranceFuzzy {
+		opts.MaxDistance = defaultMaxDistance
+		if d := v.Get("distance"); d != "" {
+			distance, err := strconv.Atoi(d)
+			if err != nil || distance < 1 || distance > maxMaxDistance {
+				return opts, fmt.Errorf("distance must be between 1 and %d", maxMaxDistance)
+			}
+			opts.MaxDistance = distance
+		}
+	}
+	return opts, nil
+}
+
+// query re-encodes the options for the quiz page to pass on to the quiz API
+func (opts quizOptions) query() string {
+	v := url.Values{}
+	if opts.Challenge != "" {
+		v.Set("challenge", opts.Challenge)
+	}
+	if opts.Assignment > 0 {
+		v.Set("assignment", strconv.Itoa(opts.Assignment))
+	}
+	if opts.Source != sourceAll {
+		v.Set("source", opts.Source)
+	}
+	if opts.MistakesDays > 0 {
+		v.Set("days", strconv.Itoa(opts.MistakesDays))
+	}
+	if opts.MistakesQuizzes > 0 {
+		v.Set("quizzes", strconv.Itoa(opts.MistakesQuizzes))
+	}
+	if opts.ListID > 0 {
+		v.Set("list", strconv.Itoa(opts.ListID))
+	}
+	if len(opts.Tags) > 0 {
+		v.Set("tags", strings.Join(opts.Tags, ","))
+	}
+	if opts.Direction != directionForward && opts.Format != formatTyped {
+		v.Set("direction", opts.Direction)
+	}
+	if opts.Distractors != "" && opts.Distractors != distractorsMeanings {
+		v.Set("distractors", opts.Distractors)
+	}
+	if opts.Choices > 0 {
+		v.Set("choices", strconv.Itoa(opts.Choices))
+	}
+	if opts.DistractorPool > 0 {
+		v.Set("pool", strconv.Itoa(opts.DistractorPool))
+	}
+	if opts.Format != formatChoice {
+		v.Set("format", opts.Format)
+		v.Set("tolerance", opts.Tolerance)
+	}
+	if opts.Tolerance == toleranceFuzzy {
+		v.Set("distance", strconv.Itoa(opts.MaxDistance))
+	}
+	if opts.Feedback {
+		v.Set("feedback", "true")
+	}
+	if opts.TimeLimit > 0 {
+		v.Set("limit", strconv.Itoa(opts.TimeLimit))
+	}
+	if opts.QuestionTimeLimit > 0 {
+		v.Set("question_limit", strconv.Itoa(opts.QuestionTimeLimit))
+	}
+	if len(v) == 0 {
+		return ""
+	}
+	return "?" + v.Encode()
+}
+
+func optionalPositiveInt(v url.Values, key string) (int, error) {
+	s := v.Get(key)
+	if s == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("%s must be a positive number", key)
+	}
+	return i, nil
+}
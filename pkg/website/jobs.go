@@ -0,0 +1,51 @@
+package website
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type backgroundJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+// AddBackgroundJob registers a job to run every interval while the website is serving;
+// jobs start with Serve/ServeWithCtx and are stopped before they return
+func (ws *Website) AddBackgroundJob(name string, interval time.Duration, run func(ctx context.Context) error) {
+	ws.jobs = append(ws.jobs, backgroundJob{
+		name:     name,
+		interval: interval,
+		run:      run,
+	})
+}
+
+func (ws *Website) startBackgroundJobs(ctx context.Context) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	for _, job := range ws.jobs {
+		wg.Add(1)
+		go func(job backgroundJob) {
+			defer wg.Done()
+			job.loop(ctx)
+		}(job)
+	}
+	return wg
+}
+
+func (job backgroundJob) loop(ctx context.Context) {
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+	for {
+		if err := job.run(ctx); err != nil {
+			log.Printf("background job %s failed: %v", job.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
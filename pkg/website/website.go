@@ -54,6 +54,7 @@ type Website struct {
 	cookieName     string
 	cookieTimeout  time.Duration
 	websiteContent embed.FS
+	jobs           []backgroundJob
 }
 
 func (ws *Website) Router() *mux.Router {
@@ -83,6 +84,12 @@ func (ws *Website) ServeWithCtx(ctx context.Context, port int) error {
 		Addr:    addr,
 		Handler: ws.r,
 	}
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	jobs := ws.startBackgroundJobs(jobsCtx)
+	defer func() {
+		stopJobs()
+		jobs.Wait()
+	}()
 	errCh := make(chan error, 1)
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -0,0 +1,217 @@
+package wordlist
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func setupQuizProgress(ws *website.Website, quizAPIGET, quizAPIPOST *mux.Router) {
+	quizAPIGET.HandleFunc("/unfinished/", func(w http.ResponseWriter, r *http.Request) {
+		unfinished, err := unfinishedQuizzes(ws.DB(), ws.AuthenticatedUser(r))
+		if err != nil {
+			log.Printf("error reading unfinished quizzes: %v", err)
+			http.Error(w, "unable to read unfinished quizzes", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(unfinished); err != nil {
+			log.Printf("error encoding unfinished quizzes: %v", err)
+			http.Error(w, "unable to read unfinished quizzes", http.StatusInternalServerError)
+			return
+		}
+	})
+
+	quizAPIGET.HandleFunc("/resume/{session}", func(w http.ResponseWriter, r *http.Request) {
+		oq, err := loadOngoingQuiz(ws.DB(), ws.AuthenticatedUser(r), mux.Vars(r)["session"])
+		if err != nil {
+			quizError(w, err, "error resuming quiz")
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(oq.quiz()); err != nil {
+			log.Printf("error encoding resumed quiz: %v", err)
+			http.Error(w, "error resuming quiz", http.StatusInternalServerError)
+			return
+		}
+	})
+
+	quizAPIPOST.HandleFunc("/hint/", func(w http.ResponseWriter, r *http.Request) {
+		req := HintRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Printf("error decoding hint request as JSON: %v", err)
+			http.Error(w, "error giving hint", http.StatusBadRequest)
+			return
+		}
+		hint, err := takeHint(ws.DB(), ws.AuthenticatedUser(r), req)
+		if err != nil {
+			quizError(w, err, "error giving hint")
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(hint); err != nil {
+			log.Printf("error encoding hint: %v", err)
+			http.Error(w, "error giving hint", http.StatusInternalServerError)
+			return
+		}
+	})
+
+	quizAPIPOST.HandleFunc("/answer/", func(w http.ResponseWriter, r *http.Request) {
+		answer := SessionAnswer{}
+		if err := json.NewDecoder(r.Body).Decode(&answer); err != nil {
+			log.Printf("error decoding answer as JSON: %v", err)
+			http.Error(w, "error saving answer", http.StatusBadRequest)
+			return
+		}
+		feedback, err := recordAnswer(ws.DB(), ws.AuthenticatedUser(r), answer)
+		if err != nil {
+			quizError(w, err, "error saving answer")
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if feedback == nil {
+			w.Write([]byte("{}"))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(feedback); err != nil {
+			log.Printf("error encoding answer feedback: %v", err)
+			http.Error(w, "error saving answer", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// loadOngoingQuiz loads an ongoing quiz of the user with its questions in the order they were asked
+func loadOngoingQuiz(db *gorm.DB, user *website.User, session string) (*OngoingQuiz, error) {
+	oq := &OngoingQuiz{}
+	result := db.Preload("OngoingQuizQuestions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Limit(1).Find(oq, "session = ?", session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		completedQuiz := &CompletedQuiz{}
+		result = db.Limit(1).Find(completedQuiz, "session = ?", session)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		switch {
+		case result.RowsAffected == 0:
+			return nil, errQuizNotFound
+		case completedQuiz.Abandoned:
+			return nil, errQuizExpired
+		default:
+			return nil, errQuizAlreadySubmitted
+		}
+	}
+	if oq.UserID != user.ID {
+		return nil, errQuizNotOwned
+	}
+	return oq, nil
+}
+
+// recordAnswer saves an answer to a question of an ongoing quiz; quizzes with feedback
+// get the answer graded and can't have it changed afterwards
+func recordAnswer(db *gorm.DB, user *website.User, answer SessionAnswer) (*AnswerFeedback, error) {
+	oq, err := loadOngoingQuiz(db, user, answer.Session)
+	if err != nil {
+		return nil, err
+	}
+	oqq, err := oq.question(answer.QuestionID)
+	if err != nil {
+		return nil, err
+	}
+	if oq.Feedback && oqq.Answered {
+		return nil, errAnswerLocked
+	}
+	chosen, err := oqq.chosen(answer.Answer)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !oq.inTime(oqq, now) {
+		return nil, errTimeUp
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(oqq)
+		if oq.Feedback {
+			query = query.Where("answered = ?", false)
+		}
+		result := query.Updates(map[string]interface{}{
+			"answered":        true,
+			"answer":          chosen,
+			"answered_at":     now,
+			"response_millis": oq.responseMillis(oqq, answer.ResponseMillis, now),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAnswerLocked
+		}
+		return tx.Model(oq).Update("updated_at", now).Error
+	})
+	if err != nil || !oq.Feedback {
+		return nil, err
+	}
+	outcome := oq.grade(*oqq, chosen)
+	return &AnswerFeedback{
+		Outcome: outcome,
+		Correct: outcome == outcomeCorrect,
+		Word:    oqq.Word,
+		Meaning: oqq.Meaning,
+	}, nil
+}
+
+func unfinishedQuizzes(db *gorm.DB, user *website.User) ([]UnfinishedQuiz, error) {
+	ongoing := []OngoingQuiz{}
+	result := db.Preload("OngoingQuizQuestions").Where("user_id = ?", user.ID).Order("created_at desc").Find(&ongoing)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	unfinished := make([]UnfinishedQuiz, 0, len(ongoing))
+	for _, oq := range ongoing {
+		answered := 0
+		for _, oqq := range oq.OngoingQuizQuestions {
+			if oqq.Answered {
+				answered++
+			}
+		}
+		unfinished = append(unfinished, UnfinishedQuiz{
+			Session:           oq.Session,
+			Direction:         oq.Direction,
+			Format:            oq.Format,
+			CreatedAt:         oq.CreatedAt,
+			TotalQuestions:    len(oq.OngoingQuizQuestions),
+			AnsweredQuestions: answered,
+		})
+	}
+	return unfinished, nil
+}
+
+type SessionAnswer struct {
+	Session string
+	Answer
+}
+
+type AnswerFeedback struct {
+	Outcome string
+	Correct bool
+	Word    string
+	Meaning string
+}
+
+type UnfinishedQuiz struct {
+	Session           string
+	Direction         string
+	Format            string
+	CreatedAt         time.Time
+	TotalQuestions    int
+	AnsweredQuestions int
+}
@@ -2,12 +2,15 @@ package wordlist
 
 import (
 	"bytes"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/arunsworld/wordlist/pkg/website"
@@ -16,19 +19,113 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupQuiz(ws *website.Website) {
+type QuizConfig struct {
+	AbandonAfter    time.Duration // ongoing quizzes untouched for longer than this are expired; 0 keeps them forever
+	RecordAbandoned bool          // expired quizzes are kept as abandoned completed quizzes
+
+	Choices        int // choices offered per multiple choice question unless the quiz asks otherwise; 5 if unset
+	MaxChoices     int // most choices a quiz may ask for; 8 if unset
+	MinQuestions   int // 1 if unset
+	MaxQuestions   int // 50 if unset
+	DistractorPool int // how many of the candidates closest to the answer distractors are drawn from; 0 for three times as many as needed
+
+	HintPenalty float64 // what a correct answer loses from its point for each hint used; 0 for nothing
+}
+
+func (c QuizConfig) withDefaults() QuizConfig {
+	if c.Choices == 0 {
+		c.Choices = 5
+	}
+	if c.MaxChoices == 0 {
+		c.MaxChoices = 8
+	}
+	if c.MinQuestions == 0 {
+		c.MinQuestions = 1
+	}
+	if c.MaxQuestions == 0 {
+		c.MaxQuestions = 50
+	}
+	return c
+}
+
+// validate rejects settings, after defaults, that would make for broken quizzes
+func (c QuizConfig) validate() error {
+	switch {
+	case c.AbandonAfter < 0:
+		return fmt.Errorf("invalid quiz config: abandon after %v is negative", c.AbandonAfter)
+	case c.Choices < 2:
+		return fmt.Errorf("invalid quiz config: %d choices, a question needs at least 2", c.Choices)
+	case c.MaxChoices < c.Choices:
+		return fmt.Errorf("invalid quiz config: at most %d choices but %d by default", c.MaxChoices, c.Choices)
+	case c.MinQuestions < 1:
+		return fmt.Errorf("invalid quiz config: at least %d questions, a quiz needs 1", c.MinQuestions)
+	case c.MaxQuestions < c.MinQuestions:
+		return fmt.Errorf("invalid quiz config: at most %d questions but at least %d", c.MaxQuestions, c.MinQuestions)
+	case c.DistractorPool < 0:
+		return fmt.Errorf("invalid quiz config: distractor pool %d is negative", c.DistractorPool)
+	case c.HintPenalty < 0 || c.HintPenalty > 1:
+		return fmt.Errorf("invalid quiz config: hint penalty %v is not between 0 and 1", c.HintPenalty)
+	}
+	return nil
+}
+
+// quizLength parses the number of questions asked for, which must be within the configured limits
+func (c QuizConfig) quizLength(s string) (int, error) {
+	count, err := strconv.Atoi(s)
+	if err != nil || count < c.MinQuestions || count > c.MaxQuestions {
+		return 0, fmt.Errorf("quiz length must be a number between %d and %d", c.MinQuestions, c.MaxQuestions)
+	}
+	return count, nil
+}
+
+func SetupQuiz(ws *website.Website, config QuizConfig) {
+	config = config.withDefaults()
+	if err := config.validate(); err != nil {
+		panic(err)
+	}
 	if err := ws.DB().AutoMigrate(&OngoingQuiz{}); err != nil {
 		panic(err)
 	}
 	if err := ws.DB().AutoMigrate(&OngoingQuizQuestion{}); err != nil {
 		panic(err)
 	}
+	scoresMissing := !ws.DB().Migrator().HasColumn(&CompletedQuiz{}, "Score")
 	if err := ws.DB().AutoMigrate(&CompletedQuiz{}); err != nil {
 		panic(err)
 	}
 	if err := ws.DB().AutoMigrate(&IncorrectWord{}); err != nil {
 		panic(err)
 	}
+	if err := ws.DB().AutoMigrate(&CompletedQuizAnswer{}); err != nil {
+		panic(err)
+	}
+	if scoresMissing {
+		if err := backfillScores(ws.DB()); err != nil {
+			panic(err)
+		}
+	}
+	separateSkips := !ws.DB().Migrator().HasTable(&SkippedWord{})
+	if err := ws.DB().AutoMigrate(&SkippedWord{}); err != nil {
+		panic(err)
+	}
+	if separateSkips {
+		if err := separateSkippedWords(ws.DB()); err != nil {
+			panic(err)
+		}
+	}
+	backfillReviews := !ws.DB().Migrator().HasTable(&WordReview{})
+	if err := ws.DB().AutoMigrate(&WordReview{}); err != nil {
+		panic(err)
+	}
+	if backfillReviews {
+		if err := backfillWordReviews(ws.DB()); err != nil {
+			panic(err)
+		}
+	}
+
+	if config.AbandonAfter > 0 {
+		ws.AddBackgroundJob("abandoned quiz sweeper", sweepInterval(config.AbandonAfter), abandonedQuizSweeper(ws.DB(), config))
+	}
 
 	quizHTML, err := ws.WebsiteContent().ReadFile("web/html/quiz.html")
 	if err != nil {
@@ -39,17 +136,22 @@ func SetupQuiz(ws *website.Website) {
 	quiz.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{}))
 	quiz.HandleFunc("/{count_str}", func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
-		count, err := strconv.Atoi(vars["count_str"])
+		count, err := config.quizLength(vars["count_str"])
 		if err != nil {
 			log.Printf("error creating a new quiz: %v", err)
-			http.Error(w, "error creating new quiz", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if count > 50 {
-			count = 50
+		opts, err := parseQuizOptions(r.URL.Query(), config)
+		if err != nil {
+			log.Printf("error creating a new quiz: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		page := bytes.ReplaceAll(quizHTML, []byte("[[COUNT]]"), []byte(strconv.Itoa(count)))
+		page = bytes.ReplaceAll(page, []byte("[[QUERY]]"), []byte(opts.query()))
 		w.Header().Set("Content-Type", "text/html")
-		w.Write(bytes.ReplaceAll(quizHTML, []byte("[[COUNT]]"), []byte(strconv.Itoa(count))))
+		w.Write(page)
 	})
 
 	quizAPIGET := ws.Router().PathPrefix("/quiz-api/").Methods("GET").Subrouter()
@@ -59,23 +161,27 @@ func SetupQuiz(ws *website.Website) {
 	quizAPIPOST.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{IsForAPI: true}))
 
 	quizAPIGET.HandleFunc("/new/{count_str}", func(w http.ResponseWriter, r *http.Request) {
-		qs, err := newQuizSession(ws.DB())
+		vars := mux.Vars(r)
+		count, err := config.quizLength(vars["count_str"])
 		if err != nil {
-			log.Printf("error creating new quiz session: %v", err)
-			http.Error(w, "error creating new quiz", http.StatusInternalServerError)
+			log.Printf("error creating new quiz session - bad count: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		vars := mux.Vars(r)
-		count, err := strconv.Atoi(vars["count_str"])
+		opts, err := parseQuizOptions(r.URL.Query(), config)
 		if err != nil {
-			log.Printf("error creating new quiz session - unable to parse count: %v", err)
-			http.Error(w, "error creating new quiz", http.StatusInternalServerError)
+			log.Printf("error creating new quiz session - bad options: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		quiz, err := qs.newQuiz(count, ws.AuthenticatedUser(r))
+		qs, err := newQuizSession(ws.DB(), config, opts.Tags)
 		if err != nil {
-			log.Printf("error creating new quiz from session: %v", err)
-			http.Error(w, "error creating new quiz", http.StatusInternalServerError)
+			quizError(w, err, "error creating new quiz")
+			return
+		}
+		quiz, err := qs.newQuiz(count, ws.AuthenticatedUser(r), opts)
+		if err != nil {
+			quizError(w, err, "error creating new quiz")
 			return
 		}
 		w.Header().Set("Content-Type", "application/javascript")
@@ -94,10 +200,9 @@ func SetupQuiz(ws *website.Website) {
 			http.Error(w, "error saving quiz results", http.StatusInternalServerError)
 			return
 		}
-		resp, err := processAnswers(ws.DB(), answers)
+		resp, err := processAnswers(ws.DB(), ws.AuthenticatedUser(r), answers)
 		if err != nil {
-			log.Printf("error processing answers: %v", err)
-			http.Error(w, "error saving quiz results", http.StatusInternalServerError)
+			quizError(w, err, "error saving quiz results")
 			return
 		}
 		w.Header().Set("Content-Type", "application/javascript")
@@ -108,173 +213,771 @@ func SetupQuiz(ws *website.Website) {
 		}
 	})
 
+	setupQuizProgress(ws, quizAPIGET, quizAPIPOST)
+	quizAPIGET.HandleFunc("/tags/", func(w http.ResponseWriter, r *http.Request) {
+		writeTagCounts(ws.DB(), w)
+	})
+
+	setupChallenges(ws, config, quizAPIGET, quizAPIPOST)
+	setupAssignments(ws, config, quizAPIGET)
+}
+
+// quizError sends errors the learner can act on as they are and logs anything else behind msg
+func quizError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, errQuizNotOwned):
+		log.Printf("WARNING: %s: %v", msg, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, errQuizNotFound), errors.Is(err, errQuizAlreadySubmitted), errors.Is(err, errQuizExpired),
+		errors.Is(err, errInvalidAnswer), errors.Is(err, errQuestionNotFound), errors.Is(err, errAnswerLocked),
+		errors.Is(err, errTimeUp), errors.Is(err, errHintUnavailable), errors.Is(err, errChallengeUnavailable),
+		errors.Is(err, errNoWordsDue), errors.Is(err, errNoMistakes), errors.Is(err, errNotEnoughWords),
+		errors.Is(err, errTagNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errChallengeNotFound), errors.Is(err, errAssignmentNotFound), errors.Is(err, errListNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", msg, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
 }
 
-func newQuizSession(db *gorm.DB) (quizSession, error) {
+// newQuizSession sets up the distinct words and meanings choices are drawn from, of only the words
+// carrying any of the tags if there are any
+func newQuizSession(db *gorm.DB, config QuizConfig, tags []string) (quizSession, error) {
 	qs := quizSession{
-		db:  db,
-		rnd: rand.New(rand.NewSource(time.Now().UnixNano())),
+		db:     db,
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		config: config,
+		tags:   tags,
 	}
-	result := db.Find(&qs.allWords)
-	if result.Error != nil {
-		return qs, result.Error
+	if err := checkTags(db, tags); err != nil {
+		return qs, err
 	}
-	for _, w := range qs.allWords {
-		qs.allMeaningsAndWords = append(qs.allMeaningsAndWords, w.Word, w.Meaning)
+	allWords, err := wordIndex.all(db)
+	if err != nil {
+		return qs, err
 	}
+	if len(tags) > 0 {
+		allWords = tagged(allWords, tags)
+	}
+	qs.setWords(allWords)
 	return qs, nil
 }
 
+// setWords makes the words those the session asks about and draws choices from
+func (qs *quizSession) setWords(allWords []Word) {
+	meanings, wordNames := map[string]struct{}{}, map[string]struct{}{}
+	for _, w := range allWords {
+		for _, m := range w.meanings() {
+			if _, ok := meanings[m]; !ok {
+				qs.allMeanings = append(qs.allMeanings, m)
+				meanings[m] = struct{}{}
+			}
+		}
+		if _, ok := wordNames[w.Word]; !ok {
+			qs.allWordNames = append(qs.allWordNames, w.Word)
+			wordNames[w.Word] = struct{}{}
+		}
+	}
+	qs.allWords = allWords
+	qs.allMeaningsAndWords = append(append([]string{}, qs.allWordNames...), qs.allMeanings...)
+}
+
 type quizSession struct {
 	db                  *gorm.DB
 	rnd                 *rand.Rand
+	config              QuizConfig
 	allWords            []Word
 	allMeaningsAndWords []string
+	allMeanings         []string
+	allWordNames        []string
+	tags                []string // the words are limited to
 }
 
-func (qs quizSession) randomMeanings(count int, meaning string, word string) []string {
+// randomChoices picks up to count different entries of pool at random, leaving out those to ignore;
+// it goes through pool in a random order once, so it ends however few entries qualify
+func (qs quizSession) randomChoices(pool []string, count int, ignore ...string) []string {
 	result := make([]string, 0, count)
-	result = append(result, meaning)
-	ignoreWords := map[string]struct{}{meaning: {}, word: {}}
-	for len(result) < count {
-		w := qs.allMeaningsAndWords[qs.rnd.Intn(len(qs.allMeaningsAndWords))]
-		_, ok := ignoreWords[w]
-		if ok {
+	ignoreWords := map[string]struct{}{}
+	for _, w := range ignore {
+		ignoreWords[w] = struct{}{}
+	}
+	for _, i := range qs.rnd.Perm(len(pool)) {
+		if len(result) == count {
+			break
+		}
+		w := pool[i]
+		if _, ok := ignoreWords[w]; ok {
 			continue
 		}
 		result = append(result, w)
 		ignoreWords[w] = struct{}{}
 	}
-	rand.Shuffle(len(result), func(i, j int) {
-		result[i], result[j] = result[j], result[i]
-	})
 	return result
 }
 
-func (qs quizSession) newQuiz(count int, user *website.User) (Quiz, error) {
+// candidateWords samples up to count words the quiz may ask in the order they should be asked
+func (qs quizSession) candidateWords(opts quizOptions, user *website.User, count int) ([]Word, error) {
+	switch opts.Source {
+	case sourceDue:
+		ids := []int{}
+		if result := dueWordIDs(qs.db, user.ID, time.Now()).Pluck("word_id", &ids); result.Error != nil {
+			return nil, result.Error
+		}
+		candidates, err := qs.wordsByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) == 0 {
+			return nil, errNoWordsDue
+		}
+		return qs.sample(candidates, count), nil
+	case sourceMistakes:
+		missed, err := missedWords(qs.db, user.ID, opts, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]int, 0, len(missed))
+		for id := range missed {
+			ids = append(ids, id)
+		}
+		candidates, err := qs.wordsByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidates) == 0 {
+			return nil, errNoMistakes
+		}
+		candidates = weightedShuffle(qs.rnd, candidates, func(w Word) int {
+			return missed[int(w.ID)]
+		})
+		if len(candidates) > count {
+			candidates = candidates[:count]
+		}
+		return candidates, nil
+	case sourceList:
+		ids, err := listWordIDs(qs.db, user, opts.ListID)
+		if err != nil {
+			return nil, err
+		}
+		candidates, err := qs.wordsByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		return qs.sample(candidates, count), nil
+	case sourceAssignment:
+		return qs.sample(opts.words, count), nil
+	default:
+		return qs.sample(qs.allWords, count), nil
+	}
+}
+
+// wordsByIDs looks up the words the session may ask about
+func (qs quizSession) wordsByIDs(ids []int) ([]Word, error) {
+	words, err := wordIndex.byIDs(qs.db, ids)
+	if err != nil || len(qs.tags) == 0 {
+		return words, err
+	}
+	return tagged(words, qs.tags), nil
+}
+
+// sample picks up to count of the words at random, without replacement
+func (qs quizSession) sample(words []Word, count int) []Word {
+	if count > len(words) {
+		count = len(words)
+	}
+	sampled := make([]Word, 0, count)
+	for _, i := range qs.rnd.Perm(len(words))[:count] {
+		sampled = append(sampled, words[i])
+	}
+	return sampled
+}
+
+func (qs quizSession) newQuiz(count int, user *website.User, opts quizOptions) (Quiz, error) {
 	if user == nil {
 		return Quiz{}, fmt.Errorf("user not found")
 	}
-	questions := make([]Question, 0, count)
-	ongoingQuestions := make([]OngoingQuizQuestion, 0, count)
-	ignoreWords := map[string]struct{}{}
-	for len(questions) < count {
-		w := qs.allWords[qs.rnd.Intn(len(qs.allWords))]
-		_, ok := ignoreWords[w.Word]
-		if ok {
-			continue
+	if opts.Challenge != "" {
+		return newChallengeQuiz(qs.db, user, opts.Challenge, qs.config)
+	}
+	if opts.Assignment != 0 {
+		var err error
+		if opts, err = assignmentOptions(qs.db, user, opts.Assignment, qs.config); err != nil {
+			return Quiz{}, err
 		}
-		questions = append(questions, Question{
-			Word:    w.Word,
-			Choices: qs.randomMeanings(5, w.Meaning, w.Word),
-		})
-		ongoingQuestions = append(ongoingQuestions, OngoingQuizQuestion{
-			Word:    w.Word,
-			Meaning: w.Meaning,
-			WordID:  int(w.ID),
-		})
-		ignoreWords[w.Word] = struct{}{}
+		count = len(opts.words)
 	}
-	quiz := Quiz{
-		Session:   uuid.NewString(),
-		Questions: questions,
+	ongoingQuestions, err := qs.questions(count, user, opts)
+	if err != nil {
+		return Quiz{}, err
 	}
 	oq := OngoingQuiz{
-		Session:              quiz.Session,
+		Session:              uuid.NewString(),
 		OngoingQuizQuestions: ongoingQuestions,
 		UserID:               user.ID,
+		Direction:            opts.Direction,
+		Format:               opts.Format,
+		Tolerance:            opts.Tolerance,
+		MaxDistance:          opts.MaxDistance,
+		Feedback:             opts.Feedback,
+		Distractors:          opts.Distractors,
+		TimeLimit:            opts.TimeLimit,
+		QuestionTimeLimit:    opts.QuestionTimeLimit,
+		HintPenalty:          qs.config.HintPenalty,
+		AssignmentID:         uint(opts.Assignment),
 	}
 	result := qs.db.Create(&oq)
 	if result.Error != nil {
 		return Quiz{}, result.Error
 	}
-	return quiz, nil
+	return oq.quiz(), nil
 }
 
-func processAnswers(db *gorm.DB, answers Answers) (QuizSaveResponse, error) {
-	ongoingQuiz := &OngoingQuiz{}
-	result := db.Preload("OngoingQuizQuestions").First(&ongoingQuiz, "session = ?", answers.Session)
-	if result.Error != nil {
-		return QuizSaveResponse{}, result.Error
+// questions picks the words to ask and the choices to offer for each
+func (qs quizSession) questions(count int, user *website.User, opts quizOptions) ([]OngoingQuizQuestion, error) {
+	candidates, err := qs.candidateWords(opts, user, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) < qs.config.MinQuestions {
+		return nil, fmt.Errorf("%w: a quiz needs at least %d", errNotEnoughWords, qs.config.MinQuestions)
+	}
+	if count > len(candidates) {
+		// only words due or missed make for a shorter quiz than asked; otherwise the list is too small
+		if opts.Source != sourceDue && opts.Source != sourceMistakes {
+			return nil, fmt.Errorf("%w: asked for %d questions but there are only %d words", errNotEnoughWords, count, len(candidates))
+		}
+		count = len(candidates)
+	}
+	choices := opts.Choices
+	if choices == 0 {
+		choices = qs.config.Choices
+	}
+	var distractors distractorStrategy
+	if opts.Format != formatTyped {
+		distractors, err = qs.distractorStrategy(opts, user, choices)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ongoingQuestions := make([]OngoingQuizQuestion, 0, count)
+	ignoreWords := map[string]struct{}{}
+	for _, w := range candidates {
+		if len(ongoingQuestions) == count {
+			break
+		}
+		_, ok := ignoreWords[w.Word]
+		if ok {
+			continue
+		}
+		sense := w.sense(qs.rnd)
+		oqq := OngoingQuizQuestion{
+			Word:    w.Word,
+			Meaning: sense.Meaning,
+			WordID:  int(w.ID),
+			SenseID: sense.ID,
+		}
+		if opts.Format != formatTyped {
+			// words or senses that are also right answers leave fewer to choose from than the pool has
+			oqq.Choices = qs.choices(distractors, w, sense, oqq.expected(opts.Direction), choices)
+			if len(oqq.Choices) < choices {
+				return nil, fmt.Errorf("%w: %d choices are needed but only %d can be offered for %s",
+					errNotEnoughWords, choices, len(oqq.Choices), w.Word)
+			}
+		}
+		ongoingQuestions = append(ongoingQuestions, oqq)
+		ignoreWords[w.Word] = struct{}{}
+	}
+	return ongoingQuestions, nil
+}
+
+var (
+	errQuizNotFound         = errors.New("quiz not found")
+	errQuizNotOwned         = errors.New("quiz belongs to another user")
+	errQuizAlreadySubmitted = errors.New("quiz has already been submitted")
+	errInvalidAnswer        = errors.New("answer was not one of the offered choices")
+	errQuizExpired          = errors.New("quiz has expired")
+	errQuestionNotFound     = errors.New("question is not part of the quiz")
+	errAnswerLocked         = errors.New("question has already been answered")
+	errNotEnoughWords       = errors.New("not enough words in the word list")
+)
+
+func processAnswers(db *gorm.DB, user *website.User, answers Answers) (QuizSaveResponse, error) {
+	if user == nil {
+		return QuizSaveResponse{}, fmt.Errorf("user not found")
+	}
+	resp, found, err := completedQuizResponse(db, user, answers.Session)
+	if err != nil || found {
+		return resp, err
 	}
-	ongoingQuizWords := map[string]OngoingQuizQuestion{}
+	ongoingQuiz, err := loadOngoingQuiz(db, user, answers.Session)
+	if err != nil {
+		return QuizSaveResponse{}, err
+	}
+	ongoingQuizQuestions := map[uint]OngoingQuizQuestion{}
+	wordIDs := make([]int, 0, len(ongoingQuiz.OngoingQuizQuestions))
 	for _, question := range ongoingQuiz.OngoingQuizQuestions {
-		ongoingQuizWords[question.Word] = question
+		ongoingQuizQuestions[question.ID] = question
+		wordIDs = append(wordIDs, question.WordID)
+	}
+	quizWords, err := wordIndex.byIDs(db, wordIDs)
+	if err != nil {
+		return QuizSaveResponse{}, err
+	}
+	entries := make(map[int]Word, len(quizWords))
+	for _, w := range quizWords {
+		entries[int(w.ID)] = w
+	}
+	submitted := map[uint]Answer{}
+	for _, answer := range answers.Answers {
+		if _, ok := ongoingQuizQuestions[answer.QuestionID]; !ok {
+			return QuizSaveResponse{}, fmt.Errorf("question %d: %w", answer.QuestionID, errQuestionNotFound)
+		}
+		submitted[answer.QuestionID] = answer
 	}
+	now := time.Now()
 	allWords := []Answer{}
 	ia := IncorrectAnswers{}
 	iws := []IncorrectWord{}
-	for _, answer := range answers.Answers {
-		oqq, ok := ongoingQuizWords[answer.Word]
-		if !ok {
-			return QuizSaveResponse{}, fmt.Errorf("%s was not found in the session but reported in answers", answer.Word)
+	sws := []SkippedWord{}
+	cqas := make([]CompletedQuizAnswer, 0, len(ongoingQuiz.OngoingQuizQuestions))
+	qualities := map[int]int{}
+	misspelled, late := 0, 0
+	score, hintsUsed := 0.0, 0
+	hinted := []HintedWord{}
+	totalMillis, timedAnswers := 0, 0
+	// answers saved along the way stand unless resubmitted, and can't be resubmitted once feedback was given;
+	// questions left unanswered, or answered after time was up, are treated as "I don't know"
+	for position, oqq := range ongoingQuiz.OngoingQuizQuestions {
+		answer, ok := submitted[oqq.ID]
+		if ongoingQuiz.Feedback {
+			answer, ok = Answer{}, false
+		}
+		if !ok && oqq.Answered {
+			answer = oqq.savedAnswer()
+		}
+		chosen, err := oqq.chosen(answer)
+		if err != nil {
+			return QuizSaveResponse{}, fmt.Errorf("question %d: %w", answer.QuestionID, err)
+		}
+		answeredAt, reportedMillis := now, answer.ResponseMillis
+		if oqq.Answered && oqq.AnsweredAt != nil && chosen == oqq.Answer {
+			answeredAt = *oqq.AnsweredAt
+			if reportedMillis == 0 {
+				reportedMillis = oqq.ResponseMillis
+			}
+		}
+		responseMillis := 0
+		if ok || oqq.Answered {
+			responseMillis = ongoingQuiz.responseMillis(&oqq, reportedMillis, answeredAt)
+			totalMillis += responseMillis
+			timedAnswers++
+		}
+		if chosen != "" && !ongoingQuiz.inTime(&oqq, answeredAt) {
+			chosen = ""
+			late++
 		}
-		if answer.Answer != oqq.Meaning {
-			ia = append(ia, IncorrectAnswer{
-				Word:    answer.Word,
+		outcome := ongoingQuiz.grade(oqq, chosen)
+		// hints make a correct answer worth less, to the learner's score and for spaced repetition
+		quality, questionScore := 5-len(oqq.Hints), 0.0
+		if quality < 3 {
+			quality = 3
+		}
+		if outcome == outcomeCorrect {
+			questionScore = hintedScore(len(oqq.Hints), ongoingQuiz.HintPenalty)
+		}
+		score += questionScore
+		if len(oqq.Hints) > 0 {
+			hintsUsed += len(oqq.Hints)
+			hinted = append(hinted, HintedWord{Word: oqq.Word, Hints: oqq.Hints})
+		}
+		switch outcome {
+		case outcomeMisspelled:
+			quality = 3
+			misspelled++
+		case outcomeIncorrect:
+			quality = 1
+		case outcomeSkipped:
+			quality = 0
+		}
+		if outcome != outcomeCorrect {
+			missed := IncorrectAnswer{
+				Word:    oqq.Word,
 				Meaning: oqq.Meaning,
-				Chosen:  answer.Answer,
+				Chosen:  chosen,
+				Outcome: outcome,
+			}
+			missed.describe(entries[oqq.WordID], oqq.SenseID)
+			ia = append(ia, missed)
+		}
+		if outcome == outcomeSkipped {
+			sws = append(sws, SkippedWord{
+				Session: ongoingQuiz.Session,
+				WordID:  oqq.WordID,
 			})
+		} else if outcome != outcomeCorrect {
 			iws = append(iws, IncorrectWord{
 				Session: ongoingQuiz.Session,
 				WordID:  oqq.WordID,
+				Outcome: outcome,
+				Chosen:  chosen,
 			})
 		}
+		qualities[oqq.WordID] = quality
+		cqas = append(cqas, CompletedQuizAnswer{
+			Session:  ongoingQuiz.Session,
+			Position: position,
+			WordID:   oqq.WordID,
+			SenseID:  oqq.SenseID,
+			Prompt:   oqq.prompt(ongoingQuiz.Direction),
+			Expected: oqq.expected(ongoingQuiz.Direction),
+			Choices:  oqq.Choices,
+			Chosen:   chosen,
+			Correct:  outcome == outcomeCorrect,
+			Skipped:  outcome == outcomeSkipped,
+			Outcome:  outcome,
+
+			ResponseMillis: responseMillis,
+			Hints:          oqq.Hints,
+			Score:          questionScore,
+		})
 		allWords = append(allWords, Answer{
-			Word:   answer.Word,
-			Answer: oqq.Meaning,
+			QuestionID: oqq.ID,
+			Word:       oqq.Word,
+			Answer:     oqq.Meaning,
 		})
 	}
-	completedQuiz := &CompletedQuiz{
-		Session:            ongoingQuiz.Session,
-		UserID:             ongoingQuiz.UserID,
-		TakenAt:            ongoingQuiz.CreatedAt,
-		TotalQuestions:     len(ongoingQuizWords),
-		IncorrectQuestions: len(iws),
+	duration := now.Sub(ongoingQuiz.CreatedAt).Round(time.Second)
+	averageMillis := 0
+	if timedAnswers > 0 {
+		averageMillis = totalMillis / timedAnswers
 	}
-	result = db.Create(completedQuiz)
-	if result.Error != nil {
-		log.Printf("WARNING: unable to save completed quiz: %v", result.Error)
+	resp = QuizSaveResponse{
+		IncorrectAnswers: ia,
+		AllWords:         allWords,
+		Time:             duration.String(),
+		DurationSeconds:  int(duration.Seconds()),
+		LateAnswers:      late,
+		SkippedAnswers:   len(sws),
+		Score:            score,
+		HintsUsed:        hintsUsed,
+		HintedWords:      hinted,
+	}
+	encodedResp, err := json.Marshal(resp)
+	if err != nil {
+		return QuizSaveResponse{}, err
+	}
+	completedQuiz := &CompletedQuiz{
+		Session:             ongoingQuiz.Session,
+		UserID:              ongoingQuiz.UserID,
+		TakenAt:             ongoingQuiz.CreatedAt,
+		Direction:           ongoingQuiz.Direction,
+		Format:              ongoingQuiz.Format,
+		Tolerance:           ongoingQuiz.Tolerance,
+		MaxDistance:         ongoingQuiz.MaxDistance,
+		Feedback:            ongoingQuiz.Feedback,
+		TotalQuestions:      len(ongoingQuizQuestions),
+		IncorrectQuestions:  len(iws),
+		SkippedQuestions:    len(sws),
+		MisspelledQuestions: misspelled,
+		TimeLimit:           ongoingQuiz.TimeLimit,
+		QuestionTimeLimit:   ongoingQuiz.QuestionTimeLimit,
+		DurationSeconds:     int(duration.Seconds()),
+		AverageMillis:       averageMillis,
+		Score:               score,
+		HintsUsed:           hintsUsed,
+		Response:            string(encodedResp),
+		ChallengeCode:       ongoingQuiz.ChallengeCode,
+		AssignmentID:        ongoingQuiz.AssignmentID,
 	}
-	if len(iws) > 0 {
-		result = db.Create(iws)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// deleting the ongoing quiz first claims it, so a concurrent submission can't complete it twice
+		result := tx.Where("session = ?", ongoingQuiz.Session).Delete(&OngoingQuiz{})
 		if result.Error != nil {
-			log.Printf("WARNING: unable to save incorrect words: %v", result.Error)
+			return result.Error
 		}
+		if result.RowsAffected == 0 {
+			return errQuizAlreadySubmitted
+		}
+		result = tx.Where("ongoing_quiz_id = ?", ongoingQuiz.Session).Delete(OngoingQuizQuestion{})
+		if result.Error != nil {
+			return result.Error
+		}
+		result = tx.Create(completedQuiz)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(iws) > 0 {
+			result = tx.Create(iws)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		if len(sws) > 0 {
+			result = tx.Create(sws)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		if len(cqas) > 0 {
+			result = tx.Create(cqas)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		for wordID, quality := range qualities {
+			if err := recordReview(tx, ongoingQuiz.UserID, wordID, quality, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// a concurrent submission of the same quiz may have won; if so answer with its result
+		if resp, found, _ := completedQuizResponse(db, user, answers.Session); found {
+			return resp, nil
+		}
+		return QuizSaveResponse{}, err
 	}
-	result = db.Where("ongoing_quiz_id = ?", ongoingQuiz.Session).Delete(OngoingQuizQuestion{})
+	return resp, nil
+}
+
+func completedQuizResponse(db *gorm.DB, user *website.User, session string) (QuizSaveResponse, bool, error) {
+	completedQuiz := &CompletedQuiz{}
+	result := db.Limit(1).Find(completedQuiz, "session = ?", session)
 	if result.Error != nil {
-		log.Printf("WARNING: unable to delete ongoing quiz questions: %v", result.Error)
+		return QuizSaveResponse{}, false, result.Error
 	}
-	result = db.Delete(ongoingQuiz)
-	if result.Error != nil {
-		log.Printf("WARNING: unable to delete ongoing quiz: %v", result.Error)
+	if result.RowsAffected == 0 {
+		return QuizSaveResponse{}, false, nil
 	}
-	duration := time.Since(ongoingQuiz.CreatedAt).Round(time.Second).String()
-	return QuizSaveResponse{IncorrectAnswers: ia, AllWords: allWords, Time: duration}, nil
+	if completedQuiz.UserID != user.ID {
+		return QuizSaveResponse{}, true, errQuizNotOwned
+	}
+	if completedQuiz.Abandoned {
+		return QuizSaveResponse{}, true, errQuizExpired
+	}
+	if completedQuiz.Response == "" {
+		return QuizSaveResponse{}, true, errQuizAlreadySubmitted
+	}
+	resp := QuizSaveResponse{}
+	if err := json.Unmarshal([]byte(completedQuiz.Response), &resp); err != nil {
+		return QuizSaveResponse{}, true, err
+	}
+	return resp, true, nil
 }
 
 type OngoingQuiz struct {
 	Session              string                `gorm:"primaryKey"`
 	OngoingQuizQuestions []OngoingQuizQuestion `gorm:"constraint:OnDelete:CASCADE;"`
 	UserID               uint
+	Direction            string `gorm:"default:forward"`
+	Format               string `gorm:"default:choice"`
+	Tolerance            string
+	MaxDistance          int
+	Feedback             bool
+	Distractors          string
+	TimeLimit            int     // seconds for the whole quiz; 0 for no limit
+	QuestionTimeLimit    int     // seconds for each question; 0 for no limit
+	HintPenalty          float64 // see QuizConfig.HintPenalty
+	ChallengeCode        string  // of the challenge being taken, if any
+	AssignmentID         uint    `gorm:"index"` // of the assignment being taken, if any
 	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (oq *OngoingQuiz) question(id uint) (*OngoingQuizQuestion, error) {
+	for i := range oq.OngoingQuizQuestions {
+		if oq.OngoingQuizQuestions[i].ID == id {
+			return &oq.OngoingQuizQuestions[i], nil
+		}
+	}
+	return nil, errQuestionNotFound
+}
+
+// quiz is the ongoing quiz as sent to the learner, with any answers saved so far
+func (oq *OngoingQuiz) quiz() Quiz {
+	quiz := Quiz{
+		Session:   oq.Session,
+		Direction: oq.Direction,
+		Format:    oq.Format,
+		Feedback:  oq.Feedback,
+		Questions: make([]Question, 0, len(oq.OngoingQuizQuestions)),
+		Answers:   []Answer{},
+
+		TimeLimit:         oq.TimeLimit,
+		QuestionTimeLimit: oq.QuestionTimeLimit,
+
+		Challenge: oq.ChallengeCode,
+	}
+	if oq.timed() {
+		quiz.SecondsLeft = oq.secondsLeft(time.Now())
+	}
+	for _, oqq := range oq.OngoingQuizQuestions {
+		quiz.Questions = append(quiz.Questions, Question{
+			ID:      oqq.ID,
+			Prompt:  oqq.prompt(oq.Direction),
+			Choices: oqq.Choices,
+			Hint:    oqq.hint(),
+		})
+		if oqq.Answered {
+			quiz.Answers = append(quiz.Answers, oqq.savedAnswer())
+		}
+	}
+	return quiz
+}
+
+func (oq *OngoingQuiz) grade(oqq OngoingQuizQuestion, answer string) string {
+	expected := oqq.expected(oq.Direction)
+	if strings.TrimSpace(answer) == "" {
+		return outcomeSkipped
+	}
+	if oq.Format == formatTyped {
+		return gradeTyped(answer, expected, oq.Tolerance, oq.MaxDistance)
+	}
+	if answer == expected {
+		return outcomeCorrect
+	}
+	return outcomeIncorrect
 }
 
 type OngoingQuizQuestion struct {
-	ID            uint `gorm:"primaryKey"`
-	OngoingQuizID string
-	Word          string
-	Meaning       string
-	WordID        int
+	ID             uint `gorm:"primaryKey"`
+	OngoingQuizID  string
+	Word           string
+	Meaning        string
+	WordID         int
+	SenseID        uint       // the sense of the word asked about; 0 from before senses
+	Choices        choiceList `gorm:"type:text"` // in the order offered
+	Answered       bool
+	Answer         string
+	AnsweredAt     *time.Time
+	ResponseMillis int
+
+	Hints       choiceList `gorm:"type:text"` // kinds of hints used, in the order asked for
+	Eliminated  choiceList `gorm:"type:text"`
+	FirstLetter string
+	Example     string // as shown, with the word blanked out when asking for the word
+}
+
+func (oqq OngoingQuizQuestion) savedAnswer() Answer {
+	return Answer{QuestionID: oqq.ID, Answer: oqq.Answer}
+}
+
+// chosen resolves an answer given either as a choice index or as text to the chosen text,
+// rejecting anything that wasn't offered; an empty answer is "I don't know". Typed answers, to
+// questions without choices, are trimmed
+func (oqq OngoingQuizQuestion) chosen(answer Answer) (string, error) {
+	if answer.Choice != nil {
+		idx := *answer.Choice
+		if idx < 0 || idx >= len(oqq.Choices) {
+			return "", errInvalidAnswer
+		}
+		if answer.Answer != "" && answer.Answer != oqq.Choices[idx] {
+			return "", errInvalidAnswer
+		}
+		return oqq.Choices[idx], nil
+	}
+	if len(oqq.Choices) == 0 {
+		return strings.TrimSpace(answer.Answer), nil
+	}
+	if answer.Answer == "" {
+		return answer.Answer, nil
+	}
+	for _, choice := range oqq.Choices {
+		if choice == answer.Answer {
+			return choice, nil
+		}
+	}
+	return "", errInvalidAnswer
+}
+
+type choiceList []string
+
+func (cl choiceList) Value() (driver.Value, error) {
+	if cl == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(cl)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (cl *choiceList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*cl = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), cl)
+	case []byte:
+		return json.Unmarshal(v, cl)
+	default:
+		return fmt.Errorf("unsupported choice list type: %T", value)
+	}
+}
+
+func (oqq OngoingQuizQuestion) prompt(direction string) string {
+	if direction == directionReverse {
+		return oqq.Meaning
+	}
+	return oqq.Word
+}
+
+func (oqq OngoingQuizQuestion) expected(direction string) string {
+	if direction == directionReverse {
+		return oqq.Word
+	}
+	return oqq.Meaning
 }
 
 type CompletedQuiz struct {
-	Session            string `gorm:"primaryKey"`
-	UserID             uint
-	TakenAt            time.Time
-	TotalQuestions     int
-	IncorrectQuestions int
+	Session             string `gorm:"primaryKey"`
+	UserID              uint
+	TakenAt             time.Time
+	Direction           string `gorm:"default:forward"`
+	Format              string `gorm:"default:choice"`
+	Tolerance           string // how typed answers were graded; empty from before it was recorded
+	MaxDistance         int
+	Feedback            bool
+	TotalQuestions      int
+	IncorrectQuestions  int // answered wrong, including misspelled
+	SkippedQuestions    int // "I don't know" or unanswered
+	MisspelledQuestions int
+	TimeLimit           int // seconds for the whole quiz; 0 for no limit
+	QuestionTimeLimit   int // seconds for each question; 0 for no limit
+	DurationSeconds     int
+	AverageMillis       int     // taken to answer a question, of the questions answered
+	Score               float64 // correct answers less penalties for hints
+	HintsUsed           int
+	Response            string `json:"-"` // QuizSaveResponse as sent, for repeated submissions
+	Abandoned           bool   // expired without being submitted
+	ChallengeCode       string `gorm:"index"` // of the challenge this was an attempt at, if any
+	AssignmentID        uint   `gorm:"index"` // of the assignment this was done for, if any
+}
+
+// CompletedQuizAnswer is how a question of a completed quiz was answered
+type CompletedQuizAnswer struct {
+	ID       uint   `gorm:"primaryKey"`
+	Session  string `gorm:"index"`
+	Position int
+	WordID   int
+	SenseID  uint
+	Prompt   string
+	Expected string
+	Choices  choiceList `gorm:"type:text"`
+	Chosen   string
+	Correct  bool
+	Skipped  bool // "I don't know"
+	Outcome  string
+
+	ResponseMillis int // taken to answer, 0 if unanswered
+
+	Hints choiceList `gorm:"type:text"`
+	Score float64
 }
 
 type IncorrectWord struct {
@@ -282,17 +985,31 @@ type IncorrectWord struct {
 	Session string
 	WordID  int
 	Word    Word
+	Outcome string `gorm:"default:incorrect"`
+	Chosen  string // the wrong answer given
 }
 
 // Javascript object
 type Quiz struct {
 	Session   string
+	Direction string
+	Format    string
+	Feedback  bool
 	Questions []Question
+	Answers   []Answer // saved so far, when resuming
+
+	TimeLimit         int // seconds for the whole quiz; 0 for no limit
+	QuestionTimeLimit int // seconds for each question; 0 for no limit
+	SecondsLeft       int // for the whole quiz, when timed
+
+	Challenge string `json:",omitempty"` // code of the challenge being taken
 }
 
 type Question struct {
-	Word    string
+	ID      uint
+	Prompt  string
 	Choices []string
+	Hint    *Hint `json:",omitempty"` // given so far, when resuming
 }
 
 type Answers struct {
@@ -301,14 +1018,24 @@ type Answers struct {
 }
 
 type Answer struct {
-	Word   string
-	Answer string
+	QuestionID uint
+	Word       string
+	Answer     string
+	Choice     *int `json:",omitempty"` // index into Question.Choices, as an alternative to Answer
+
+	ResponseMillis int `json:",omitempty"` // taken to answer, as measured by the client
 }
 
 type QuizSaveResponse struct {
 	IncorrectAnswers IncorrectAnswers
 	AllWords         []Answer
 	Time             string // humanized time
+	DurationSeconds  int
+	LateAnswers      int // answers that came in after time was up, counted as unanswered
+	SkippedAnswers   int
+	Score            float64
+	HintsUsed        int
+	HintedWords      []HintedWord
 }
 
 type IncorrectAnswers []IncorrectAnswer
@@ -317,4 +1044,10 @@ type IncorrectAnswer struct {
 	Word    string
 	Meaning string
 	Chosen  string
+	Outcome string
+
+	PartOfSpeech string   `json:",omitempty"`
+	Examples     []string `json:",omitempty"`
+	Synonyms     []string `json:",omitempty"`
+	Antonyms     []string `json:",omitempty"`
 }
@@ -0,0 +1,334 @@
+package wordlist
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// callTimeout bounds calls that once looped forever on small or duplicate-heavy word lists
+const callTimeout = time.Second
+
+// within fails the test if f doesn't return within callTimeout
+func within(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(callTimeout):
+		t.Fatalf("call did not return within %v", callTimeout)
+	}
+}
+
+func testWords(meanings ...string) []Word {
+	words := make([]Word, 0, len(meanings))
+	for i, m := range meanings {
+		id := uint(i + 1)
+		words = append(words, Word{
+			ID:      id,
+			Word:    fmt.Sprintf("word%d", id),
+			Meaning: m,
+			Senses:  []Sense{{ID: id, WordID: id, Meaning: m}},
+		})
+	}
+	return words
+}
+
+func distinctWords(n int) []Word {
+	meanings := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		meanings = append(meanings, fmt.Sprintf("meaning %d", i))
+	}
+	return testWords(meanings...)
+}
+
+func testSession(words []Word) quizSession {
+	qs := quizSession{rnd: rand.New(rand.NewSource(1)), config: QuizConfig{}.withDefaults()}
+	qs.setWords(words)
+	return qs
+}
+
+func testOptions(t *testing.T, query string) quizOptions {
+	t.Helper()
+	v, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := parseQuizOptions(v, QuizConfig{}.withDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+var (
+	sharedMeaning     = testWords("x", "x", "x", "x", "x")
+	sharedAmongOthers = testWords("a", "b", "c", "d", "e", "x", "x", "x", "x", "x")
+)
+
+// assertChoiceCounts checks every multiple choice question offers as many choices as the quiz asks for
+func assertChoiceCounts(t *testing.T, questions []OngoingQuizQuestion, opts quizOptions) {
+	t.Helper()
+	want := opts.Choices
+	if want == 0 {
+		want = QuizConfig{}.withDefaults().Choices
+	}
+	if opts.Format == formatTyped {
+		want = 0
+	}
+	for _, q := range questions {
+		if len(q.Choices) != want {
+			t.Errorf("%s: got %d choices %v, want %d", q.Word, len(q.Choices), q.Choices, want)
+		}
+	}
+}
+
+func TestQuestions(t *testing.T) {
+	tests := []struct {
+		name      string
+		words     []Word
+		count     int
+		query     string
+		wantErr   error
+		questions int
+	}{
+		{name: "one word typed", words: distinctWords(1), count: 1, query: "format=typed", questions: 1},
+		{name: "one word multiple choice", words: distinctWords(1), count: 1, wantErr: errNotEnoughWords},
+		{name: "one word asked for more", words: distinctWords(1), count: 5, query: "format=typed", wantErr: errNotEnoughWords},
+		{name: "five words share a meaning", words: sharedMeaning, count: 5, wantErr: errNotEnoughWords},
+		{name: "five words share a meaning, typed", words: sharedMeaning, count: 5, query: "format=typed", questions: 5},
+		{name: "five words share a meaning, reverse", words: sharedMeaning, count: 5, query: "direction=reverse", wantErr: errNotEnoughWords},
+		{name: "shared meaning among others", words: sharedAmongOthers, count: 10, questions: 10},
+		{name: "shared meaning among others, reverse", words: sharedAmongOthers, count: 10, query: "direction=reverse", questions: 10},
+		{name: "shared meaning leaves too few choices", words: sharedAmongOthers, count: 10, query: "direction=reverse&choices=7", wantErr: errNotEnoughWords},
+		{name: "more words than exist", words: distinctWords(6), count: 10, wantErr: errNotEnoughWords},
+		{name: "as many words as exist", words: distinctWords(6), count: 6, questions: 6},
+		{name: "similar distractors", words: distinctWords(6), count: 6, query: "distractors=similar", questions: 6},
+		{name: "mixed distractors", words: distinctWords(3), count: 3, query: "distractors=mixed", questions: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := testSession(tt.words)
+			opts := testOptions(t, tt.query)
+			var questions []OngoingQuizQuestion
+			var err error
+			within(t, func() {
+				questions, err = qs.questions(tt.count, nil, opts)
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(questions) != tt.questions {
+				t.Fatalf("got %d questions, want %d", len(questions), tt.questions)
+			}
+			assertChoiceCounts(t, questions, opts)
+		})
+	}
+}
+
+func TestQuestionChoices(t *testing.T) {
+	// word1 to word5 share their meaning, so none of them is a wrong answer to it
+	words := testWords("x", "x", "x", "x", "x", "a", "b", "c", "d", "e", "f")
+	sharing := map[string]bool{"word1": true, "word2": true, "word3": true, "word4": true, "word5": true}
+	qs := testSession(words)
+	for _, query := range []string{"", "direction=reverse", "distractors=similar", "direction=reverse&distractors=similar"} {
+		t.Run(query, func(t *testing.T) {
+			opts := testOptions(t, query)
+			questions, err := qs.questions(len(words), nil, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertChoiceCounts(t, questions, opts)
+			for _, q := range questions {
+				expected := q.expected(opts.Direction)
+				if !contains(q.Choices, expected) {
+					t.Errorf("%s: answer %q not among choices %v", q.Word, expected, q.Choices)
+				}
+				if distinct(q.Choices) != len(q.Choices) {
+					t.Errorf("%s: repeated choices %v", q.Word, q.Choices)
+				}
+				if opts.Direction != directionReverse || q.Meaning != "x" {
+					continue
+				}
+				for _, c := range q.Choices {
+					if c != expected && sharing[c] {
+						t.Errorf("%s: choices %v offer %s, which also means x", q.Word, q.Choices, c)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []Word
+		count int
+		want  int
+	}{
+		{name: "no words", words: nil, count: 5, want: 0},
+		{name: "one word", words: distinctWords(1), count: 1, want: 1},
+		{name: "more than exist", words: distinctWords(3), count: 10, want: 3},
+		{name: "fewer than exist", words: distinctWords(10), count: 4, want: 4},
+		{name: "none", words: distinctWords(10), count: 0, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := testSession(tt.words)
+			var sampled []Word
+			within(t, func() {
+				sampled = qs.sample(tt.words, tt.count)
+			})
+			if len(sampled) != tt.want {
+				t.Fatalf("got %d words, want %d", len(sampled), tt.want)
+			}
+			seen := map[uint]bool{}
+			for _, w := range sampled {
+				if seen[w.ID] {
+					t.Fatalf("word %d sampled twice", w.ID)
+				}
+				seen[w.ID] = true
+			}
+		})
+	}
+}
+
+func TestRandomChoices(t *testing.T) {
+	duplicates := make([]string, 1000)
+	for i := range duplicates {
+		duplicates[i] = "same"
+	}
+	tests := []struct {
+		name   string
+		pool   []string
+		count  int
+		ignore []string
+		want   int
+	}{
+		{name: "empty pool", pool: nil, count: 4, want: 0},
+		{name: "one entry", pool: []string{"a"}, count: 4, want: 1},
+		{name: "only entry ignored", pool: []string{"a"}, count: 4, ignore: []string{"a"}, want: 0},
+		{name: "all duplicates", pool: duplicates, count: 4, want: 1},
+		{name: "all duplicates ignored", pool: duplicates, count: 4, ignore: []string{"same"}, want: 0},
+		{name: "more than distinct", pool: []string{"a", "b", "b", "c"}, count: 4, ignore: []string{"c"}, want: 2},
+		{name: "enough", pool: []string{"a", "b", "c", "d", "e", "f"}, count: 4, ignore: []string{"a"}, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := testSession(nil)
+			var choices []string
+			within(t, func() {
+				choices = qs.randomChoices(tt.pool, tt.count, tt.ignore...)
+			})
+			if len(choices) != tt.want {
+				t.Fatalf("got %d choices %v, want %d", len(choices), choices, tt.want)
+			}
+			if distinct(choices) != len(choices) {
+				t.Fatalf("repeated choices %v", choices)
+			}
+			for _, c := range choices {
+				if contains(tt.ignore, c) {
+					t.Fatalf("ignored %q was chosen", c)
+				}
+			}
+		})
+	}
+}
+
+func TestDistractorStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		words   []Word
+		query   string
+		choices int
+		wantErr error
+	}{
+		{name: "one word", words: distinctWords(1), choices: 2, wantErr: errNotEnoughWords},
+		{name: "one word, one choice", words: distinctWords(1), choices: 1, wantErr: errNotEnoughWords},
+		{name: "one choice", words: distinctWords(5), choices: 1, wantErr: errNotEnoughWords},
+		{name: "five words share a meaning", words: sharedMeaning, choices: 2, wantErr: errNotEnoughWords},
+		// reverse questions about the shared meaning run short of choices, see TestQuestions
+		{name: "five words share a meaning, mixed", words: sharedMeaning, query: "distractors=mixed", choices: 5},
+		{name: "more choices than words", words: distinctWords(4), choices: 5, wantErr: errNotEnoughWords},
+		{name: "similar", words: distinctWords(5), query: "distractors=similar", choices: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := testSession(tt.words)
+			opts := testOptions(t, tt.query)
+			var strategy distractorStrategy
+			var err error
+			within(t, func() {
+				strategy, err = qs.distractorStrategy(opts, nil, tt.choices)
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			w := tt.words[0]
+			answer := w.Meaning
+			if opts.Direction == directionReverse {
+				answer = w.Word
+			}
+			var distractors []string
+			within(t, func() {
+				distractors = strategy.distractors(w, w.Senses[0], answer, tt.choices-1)
+			})
+			if len(distractors) != tt.choices-1 {
+				t.Fatalf("got %d distractors %v, want %d", len(distractors), distractors, tt.choices-1)
+			}
+			for _, d := range distractors {
+				if d == answer {
+					t.Fatalf("answer %q offered as a distractor", answer)
+				}
+			}
+		})
+	}
+}
+
+func TestQuizConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  QuizConfig
+		wantErr bool
+	}{
+		{name: "defaults", config: QuizConfig{}},
+		{name: "deployment flags", config: QuizConfig{Choices: 5, MaxChoices: 8, MinQuestions: 1, MaxQuestions: 50, HintPenalty: 0.25}},
+		{name: "one choice", config: QuizConfig{Choices: 1}, wantErr: true},
+		{name: "negative choices", config: QuizConfig{Choices: -5}, wantErr: true},
+		{name: "max choices of one", config: QuizConfig{MaxChoices: 1}, wantErr: true},
+		{name: "max choices below default", config: QuizConfig{Choices: 6, MaxChoices: 4}, wantErr: true},
+		{name: "negative min questions", config: QuizConfig{MinQuestions: -1}, wantErr: true},
+		{name: "min above max questions", config: QuizConfig{MinQuestions: 10, MaxQuestions: 5}, wantErr: true},
+		{name: "negative distractor pool", config: QuizConfig{DistractorPool: -1}, wantErr: true},
+		{name: "negative abandon after", config: QuizConfig{AbandonAfter: -time.Hour}, wantErr: true},
+		{name: "hint penalty above a point", config: QuizConfig{HintPenalty: 1.5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.withDefaults().validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
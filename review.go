@@ -0,0 +1,138 @@
+package wordlist
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var errNoWordsDue = errors.New("no words are due for review")
+
+const (
+	defaultEaseFactor = 2.5
+	minEaseFactor     = 1.3
+)
+
+// WordReview is the spaced-repetition (SM-2) state of a word for a user
+type WordReview struct {
+	ID          uint `gorm:"primaryKey"`
+	UserID      uint `gorm:"uniqueIndex:idx_word_review_user_word"`
+	WordID      int  `gorm:"uniqueIndex:idx_word_review_user_word"`
+	EaseFactor  float64
+	Interval    int // days
+	Repetitions int
+	DueAt       time.Time `gorm:"index"`
+	ReviewedAt  time.Time
+}
+
+// grade applies an SM-2 review with quality between 0 (blackout) and 5 (perfect)
+func (wr *WordReview) grade(quality int, now time.Time) {
+	if wr.EaseFactor == 0 {
+		wr.EaseFactor = defaultEaseFactor
+	}
+	if quality >= 3 {
+		switch wr.Repetitions {
+		case 0:
+			wr.Interval = 1
+		case 1:
+			wr.Interval = 6
+		default:
+			wr.Interval = int(math.Round(float64(wr.Interval) * wr.EaseFactor))
+		}
+		wr.Repetitions++
+	} else {
+		wr.Repetitions = 0
+		wr.Interval = 1
+	}
+	q := float64(5 - quality)
+	wr.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if wr.EaseFactor < minEaseFactor {
+		wr.EaseFactor = minEaseFactor
+	}
+	wr.ReviewedAt = now
+	wr.DueAt = now.AddDate(0, 0, wr.Interval)
+}
+
+func recordReview(db *gorm.DB, userID uint, wordID int, quality int, now time.Time) error {
+	wr := &WordReview{}
+	result := db.Where("user_id = ? AND word_id = ?", userID, wordID).Limit(1).Find(wr)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		wr = &WordReview{UserID: userID, WordID: wordID, EaseFactor: defaultEaseFactor}
+	}
+	wr.grade(quality, now)
+	return db.Save(wr).Error
+}
+
+// dueWordIDs is a query of the IDs of the user's words due for review
+func dueWordIDs(db *gorm.DB, userID uint, now time.Time) *gorm.DB {
+	return db.Model(&WordReview{}).Select("word_id").Where("user_id = ? AND due_at <= ?", userID, now)
+}
+
+// backfillWordReviews seeds review state from quizzes taken before spaced repetition existed;
+// only misses were recorded, so every missed word starts over and is due a day after its last miss
+func backfillWordReviews(db *gorm.DB) error {
+	type missedWord struct {
+		UserID   uint
+		WordID   int
+		Misses   int
+		LastMiss string
+	}
+	missed := []missedWord{}
+	result := db.Table("incorrect_words").
+		Select("completed_quizzes.user_id, incorrect_words.word_id, count(*) as misses, max(completed_quizzes.taken_at) as last_miss").
+		Joins("join completed_quizzes on completed_quizzes.session = incorrect_words.session").
+		Group("completed_quizzes.user_id, incorrect_words.word_id").
+		Scan(&missed)
+	if result.Error != nil {
+		return result.Error
+	}
+	reviews := make([]WordReview, 0, len(missed))
+	for _, m := range missed {
+		lastMiss, err := parseSQLiteTime(m.LastMiss)
+		if err != nil {
+			return err
+		}
+		ef := defaultEaseFactor - 0.2*float64(m.Misses)
+		if ef < minEaseFactor {
+			ef = minEaseFactor
+		}
+		reviews = append(reviews, WordReview{
+			UserID:     m.UserID,
+			WordID:     m.WordID,
+			EaseFactor: ef,
+			Interval:   1,
+			DueAt:      lastMiss.AddDate(0, 0, 1),
+			ReviewedAt: lastMiss,
+		})
+	}
+	if len(reviews) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(reviews, 100).Error
+}
+
+// aggregates over timestamps come back from sqlite as text in the driver's layout
+func parseSQLiteTime(s string) (time.Time, error) {
+	layouts := []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02T15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -0,0 +1,39 @@
+package wordlist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWordReviewGrade(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		review      WordReview
+		quality     int
+		interval    int
+		repetitions int
+		ease        float64
+	}{
+		{name: "first pass", review: WordReview{}, quality: 5, interval: 1, repetitions: 1, ease: 2.6},
+		{name: "second pass", review: WordReview{EaseFactor: 2.5, Interval: 1, Repetitions: 1}, quality: 4, interval: 6, repetitions: 2, ease: 2.5},
+		{name: "third pass", review: WordReview{EaseFactor: 2.5, Interval: 6, Repetitions: 2}, quality: 3, interval: 15, repetitions: 3, ease: 2.36},
+		{name: "miss starts over", review: WordReview{EaseFactor: 2.5, Interval: 15, Repetitions: 3}, quality: 1, interval: 1, repetitions: 0, ease: 1.96},
+		{name: "ease has a floor", review: WordReview{EaseFactor: 1.3, Interval: 1, Repetitions: 0}, quality: 0, interval: 1, repetitions: 0, ease: minEaseFactor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := tt.review
+			wr.grade(tt.quality, now)
+			if wr.Interval != tt.interval || wr.Repetitions != tt.repetitions {
+				t.Errorf("got interval %d and repetitions %d, want %d and %d", wr.Interval, wr.Repetitions, tt.interval, tt.repetitions)
+			}
+			if diff := wr.EaseFactor - tt.ease; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("got ease factor %v, want %v", wr.EaseFactor, tt.ease)
+			}
+			if !wr.ReviewedAt.Equal(now) || !wr.DueAt.Equal(now.AddDate(0, 0, tt.interval)) {
+				t.Errorf("got reviewed %v due %v", wr.ReviewedAt, wr.DueAt)
+			}
+		})
+	}
+}
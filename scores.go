@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
 )
 
 func SetupScores(ws *website.Website) {
@@ -14,6 +16,11 @@ func SetupScores(ws *website.Website) {
 		panic(err)
 	}
 
+	quizReviewHTML, err := ws.WebsiteContent().ReadFile("web/html/quiz-review.html")
+	if err != nil {
+		panic(err)
+	}
+
 	scores := ws.Router().Path("/scores/").Methods("GET").Subrouter()
 	scores.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{}))
 	scores.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -21,12 +28,23 @@ func SetupScores(ws *website.Website) {
 		w.Write(scoresHTML)
 	})
 
+	quizReview := ws.Router().PathPrefix("/scores/quiz/").Methods("GET").Subrouter()
+	quizReview.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{}))
+	quizReview.HandleFunc("/{session}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(quizReviewHTML)
+	})
+
 	scoresAPI := ws.Router().PathPrefix("/scores-api/").Methods("GET").Subrouter()
 	scoresAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{}))
 	scoresAPI.HandleFunc("/scores/", func(w http.ResponseWriter, r *http.Request) {
 		user := ws.AuthenticatedUser(r)
 		completedQuizes := []CompletedQuiz{}
-		result := ws.DB().Where("user_id = ?", user.ID).Find(&completedQuizes)
+		query := ws.DB().Where("user_id = ?", user.ID)
+		if direction := r.URL.Query().Get("direction"); direction != "" {
+			query = query.Where("direction = ?", direction)
+		}
+		result := query.Find(&completedQuizes)
 		if result.Error != nil {
 			log.Printf("error reading completed quizes from DB: %v", err)
 			http.Error(w, "unable to read scores", http.StatusInternalServerError)
@@ -39,4 +57,99 @@ func SetupScores(ws *website.Website) {
 			return
 		}
 	})
+	scoresAPI.HandleFunc("/quiz/{session}", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		review := QuizReview{}
+		result := ws.DB().Limit(1).Find(&review.Quiz, "session = ? AND user_id = ?", mux.Vars(r)["session"], user.ID)
+		if result.Error != nil {
+			log.Printf("error reading completed quiz from DB: %v", result.Error)
+			http.Error(w, "unable to read quiz", http.StatusInternalServerError)
+			return
+		}
+		if result.RowsAffected == 0 {
+			http.Error(w, "quiz not found", http.StatusNotFound)
+			return
+		}
+		result = ws.DB().Where("session = ?", review.Quiz.Session).Order("position").Find(&review.Answers)
+		if result.Error != nil {
+			log.Printf("error reading completed quiz answers from DB: %v", result.Error)
+			http.Error(w, "unable to read quiz", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			log.Printf("error encoding completed quiz: %v", err)
+			http.Error(w, "unable to read quiz", http.StatusInternalServerError)
+			return
+		}
+	})
+	scoresAPI.HandleFunc("/slow-words/", func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if l := r.URL.Query().Get("limit"); l != "" {
+			var err error
+			if limit, err = strconv.Atoi(l); err != nil || limit < 1 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+		}
+		slow, err := slowWords(ws.DB(), ws.AuthenticatedUser(r).ID, limit)
+		if err != nil {
+			log.Printf("error reading slow words from DB: %v", err)
+			http.Error(w, "unable to read slow words", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(slow); err != nil {
+			log.Printf("error encoding slow words: %v", err)
+			http.Error(w, "unable to read slow words", http.StatusInternalServerError)
+			return
+		}
+	})
+	scoresAPI.HandleFunc("/summary/", func(w http.ResponseWriter, r *http.Request) {
+		user := ws.AuthenticatedUser(r)
+		summaries := []ScoreSummary{}
+		result := ws.DB().Model(&CompletedQuiz{}).
+			Select("direction, format, "+
+				"sum(case when abandoned then 0 else 1 end) as quizzes, "+
+				"sum(case when abandoned then 0 else total_questions end) as total_questions, "+
+				"sum(incorrect_questions) as incorrect_questions, "+
+				"sum(skipped_questions) as skipped_questions, "+
+				"sum(misspelled_questions) as misspelled_questions, "+
+				"sum(case when abandoned then 1 else 0 end) as abandoned, "+
+				"sum(score) as score, sum(hints_used) as hints_used, "+
+				"coalesce(cast(avg(case when average_millis > 0 then average_millis end) as integer), 0) as average_millis").
+			Where("user_id = ?", user.ID).
+			Group("direction, format").
+			Scan(&summaries)
+		if result.Error != nil {
+			log.Printf("error summarising completed quizes from DB: %v", result.Error)
+			http.Error(w, "unable to read scores", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(summaries); err != nil {
+			log.Printf("error encoding score summary: %v", err)
+			http.Error(w, "unable to read scores", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+type QuizReview struct {
+	Quiz    CompletedQuiz
+	Answers []CompletedQuizAnswer
+}
+
+type ScoreSummary struct {
+	Direction           string
+	Format              string
+	Quizzes             int
+	TotalQuestions      int
+	IncorrectQuestions  int
+	SkippedQuestions    int
+	MisspelledQuestions int
+	Abandoned           int
+	AverageMillis       int // taken to answer a question
+	Score               float64
+	HintsUsed           int
 }
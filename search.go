@@ -0,0 +1,211 @@
+package wordlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const (
+	searchPageSize    = 20
+	maxSearchPageSize = 100
+	maxSearchTerms    = 8
+	maxSuggestions    = 8
+)
+
+var errBadSearchPage = errors.New("page must be a number from 1 and size from 1 to 100")
+
+// WordSearchResults is a page of the words matching a search, best matches first
+type WordSearchResults struct {
+	Query    string
+	Page     int
+	PageSize int
+	Total    int64
+	Words    []Word
+}
+
+// WordSuggestions completes a word being typed into the add form
+type WordSuggestions struct {
+	Words   []ListWord
+	Exists  bool       // the word is already in the list
+	Similar []ListWord `json:",omitempty"` // words spelt much like it, which it may be a duplicate of
+}
+
+func setupSearch(ws *website.Website, wordlistAPI *mux.Router) {
+	migrateSearch(ws.DB())
+
+	// search matches each term at the start of words or inside their meanings, e.g. ?q=abund&page=2&size=20
+	wordlistAPI.HandleFunc("/search/", func(w http.ResponseWriter, r *http.Request) {
+		terms := searchTerms(r.URL.Query().Get("q"))
+		if len(terms) == 0 {
+			http.Error(w, "nothing to search for", http.StatusBadRequest)
+			return
+		}
+		page, size, err := searchPage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results, err := search(ws.DB(), terms, page, size)
+		if err != nil {
+			log.Printf("error searching words: %v", err)
+			http.Error(w, "unable to search words", http.StatusInternalServerError)
+			return
+		}
+		results.Query = r.URL.Query().Get("q")
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Printf("error marshaling data as JSON: %v", err)
+			http.Error(w, "unable to search words", http.StatusInternalServerError)
+			return
+		}
+	})
+
+	// autocomplete serves the add form; like the words it suggests, it's for those who manage the word list
+	wordlistAPI.HandleFunc("/autocomplete/", func(w http.ResponseWriter, r *http.Request) {
+		suggestions, err := suggestWords(ws.DB(), strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q"))))
+		if err != nil {
+			log.Printf("error reading words from DB: %v", err)
+			http.Error(w, "unable to suggest words", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(suggestions); err != nil {
+			log.Printf("error marshaling data as JSON: %v", err)
+			http.Error(w, "unable to suggest words", http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// searchTerms splits the query into lower case words; anything but letters and digits separates them
+func searchTerms(q string) []string {
+	terms := []string{}
+	for _, t := range strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !contains(terms, t) && len(terms) < maxSearchTerms {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// matchTier is the SQL ranking words in column equal to one of the terms first, then those starting with
+// one, then those matching on their meanings alone, so both kinds of search rank alike
+func matchTier(column string, terms []string) (string, []interface{}) {
+	prefixes := make([]string, 0, len(terms))
+	vars := []interface{}{terms}
+	for _, t := range terms {
+		prefixes = append(prefixes, column+" LIKE ?")
+		vars = append(vars, t+"%")
+	}
+	return fmt.Sprintf("CASE WHEN %s IN ? THEN 0 WHEN %s THEN 1 ELSE 2 END", column, strings.Join(prefixes, " OR ")), vars
+}
+
+func searchPage(r *http.Request) (int, int, error) {
+	page, size := 1, searchPageSize
+	var err error
+	if p := r.URL.Query().Get("page"); p != "" {
+		if page, err = strconv.Atoi(p); err != nil || page < 1 {
+			return 0, 0, errBadSearchPage
+		}
+	}
+	if s := r.URL.Query().Get("size"); s != "" {
+		if size, err = strconv.Atoi(s); err != nil || size < 1 || size > maxSearchPageSize {
+			return 0, 0, errBadSearchPage
+		}
+	}
+	return page, size, nil
+}
+
+func search(db *gorm.DB, terms []string, page, size int) (WordSearchResults, error) {
+	results := WordSearchResults{Page: page, PageSize: size, Words: []Word{}}
+	ids, total, err := searchWords(db, terms, (page-1)*size, size)
+	if err != nil {
+		return results, err
+	}
+	results.Total = total
+	wanted := make([]int, 0, len(ids))
+	for _, id := range ids {
+		wanted = append(wanted, int(id))
+	}
+	words, err := wordIndex.byIDs(db, wanted)
+	if err != nil {
+		return results, err
+	}
+	byID := make(map[uint]Word, len(words))
+	for _, w := range words {
+		byID[w.ID] = w
+	}
+	for _, id := range ids {
+		if w, ok := byID[id]; ok {
+			results.Words = append(results.Words, w)
+		}
+	}
+	return results, nil
+}
+
+// suggestWords lists the words starting with what's been typed, and those it may be a misspelling of
+func suggestWords(db *gorm.DB, q string) (WordSuggestions, error) {
+	suggestions := WordSuggestions{Words: []ListWord{}}
+	if q == "" {
+		return suggestions, nil
+	}
+	allWords, err := wordIndex.all(db)
+	if err != nil {
+		return suggestions, err
+	}
+	sort.Slice(allWords, func(i, j int) bool { return allWords[i].Word < allWords[j].Word })
+	type similarWord struct {
+		word     ListWord
+		distance int
+	}
+	similar := []similarWord{}
+	maxDistance := similarDistance(q)
+	for _, w := range allWords {
+		entry := ListWord{ID: w.ID, Word: w.Word, Meaning: w.Meaning}
+		if w.Word == q {
+			suggestions.Exists = true
+		}
+		if strings.HasPrefix(w.Word, q) && len(suggestions.Words) < maxSuggestions {
+			suggestions.Words = append(suggestions.Words, entry)
+		}
+		if w.Word == q || maxDistance == 0 {
+			continue
+		}
+		if d := levenshtein(q, w.Word); d <= maxDistance {
+			similar = append(similar, similarWord{word: entry, distance: d})
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].distance < similar[j].distance })
+	for i, s := range similar {
+		if i == maxSuggestions {
+			break
+		}
+		suggestions.Similar = append(suggestions.Similar, s.word)
+	}
+	return suggestions, nil
+}
+
+// similarDistance is how many edits apart words may be to look alike; short words differ by too little to tell
+func similarDistance(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,76 @@
+//go:build sqlite_fts5
+// +build sqlite_fts5
+
+package wordlist
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// indexedWords is what word_search holds for each word, by its ID: the word and the meanings of its senses
+const indexedWords = `INSERT INTO word_search (rowid, word, meanings)
+	SELECT words.id, words.word,
+		coalesce((SELECT group_concat(senses.meaning, '; ') FROM senses WHERE senses.word_id = words.id), words.meaning)
+	FROM words`
+
+// migrateSearch creates the FTS5 index and rebuilds it when it's missing words,
+// e.g. those loaded by the utility, which doesn't index them
+func migrateSearch(db *gorm.DB) {
+	if err := db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS word_search USING fts5(word, meanings, prefix='2 3')").Error; err != nil {
+		panic(err)
+	}
+	var indexed, words int64
+	if err := db.Table("word_search").Count(&indexed).Error; err != nil {
+		panic(err)
+	}
+	if err := db.Model(&Word{}).Count(&words).Error; err != nil {
+		panic(err)
+	}
+	if indexed == words {
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM word_search").Error; err != nil {
+			return err
+		}
+		return tx.Exec(indexedWords).Error
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// indexWord brings the word's entry in the search index up to date with its senses
+func indexWord(tx *gorm.DB, wordID uint) error {
+	if err := tx.Exec("DELETE FROM word_search WHERE rowid = ?", wordID).Error; err != nil {
+		return err
+	}
+	return tx.Exec(indexedWords+" WHERE words.id = ?", wordID).Error
+}
+
+// searchWords finds words, or words in their meanings, that start with each term, ranked by how the
+// word matches and then by bm25, with matches on the word counting far more than those in its meanings
+func searchWords(db *gorm.DB, terms []string, offset, limit int) ([]uint, int64, error) {
+	matches := make([]string, 0, len(terms))
+	for _, t := range terms {
+		// terms are only letters and digits, so need no escaping inside quotes
+		matches = append(matches, fmt.Sprintf(`(word : "%s"* OR meanings : "%s"*)`, t, t))
+	}
+	match := strings.Join(matches, " AND ")
+
+	var total int64
+	if err := db.Table("word_search").Where("word_search MATCH ?", match).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	ids := []uint{}
+	tier, vars := matchTier("word", terms)
+	result := db.Table("word_search").Where("word_search MATCH ?", match).
+		Clauses(clause.OrderBy{Expression: clause.Expr{SQL: tier + ", bm25(word_search, 10.0, 1.0), word", Vars: vars}}).
+		Limit(limit).Offset(offset).
+		Pluck("rowid", &ids)
+	return ids, total, result.Error
+}
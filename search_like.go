@@ -0,0 +1,43 @@
+//go:build !sqlite_fts5
+// +build !sqlite_fts5
+
+package wordlist
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// without FTS5 compiled into SQLite words are searched with LIKE, so there is no index to keep
+
+func migrateSearch(db *gorm.DB) {}
+
+func indexWord(tx *gorm.DB, wordID uint) error {
+	return nil
+}
+
+// searchWords finds words that start with each term or whose meanings contain it, ranked by how the
+// word matches and then alphabetically
+func searchWords(db *gorm.DB, terms []string, offset, limit int) ([]uint, int64, error) {
+	matching := func() *gorm.DB {
+		tx := db.Model(&Word{})
+		for _, t := range terms {
+			// terms are only letters and digits, so hold no LIKE wildcards
+			tx = tx.Where("words.word LIKE ? OR words.meaning LIKE ? OR words.id IN (?)", t+"%", "%"+t+"%",
+				db.Model(&Sense{}).Select("word_id").Where("meaning LIKE ?", "%"+t+"%"))
+		}
+		return tx
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	ids := []uint{}
+	tier, vars := matchTier("words.word", terms)
+	result := matching().
+		Clauses(clause.OrderBy{Expression: clause.Expr{SQL: tier + ", words.word", Vars: vars}}).
+		Limit(limit).Offset(offset).
+		Pluck("words.id", &ids)
+	return ids, total, result.Error
+}
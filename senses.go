@@ -0,0 +1,292 @@
+package wordlist
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var (
+	errSenseNotFound  = errors.New("sense not found")
+	errLastSense      = errors.New("a word needs at least one sense")
+	errDuplicateSense = errors.New("word already has this meaning")
+)
+
+// Sense is one meaning of a word; the first, at position 0, is the primary sense that Word.Meaning mirrors
+type Sense struct {
+	ID           uint `gorm:"primaryKey"`
+	WordID       uint `gorm:"index"`
+	Meaning      string
+	PartOfSpeech string
+	Position     int
+}
+
+// meanings of all the word's senses
+func (w Word) meanings() []string {
+	if len(w.Senses) == 0 {
+		return []string{w.Meaning}
+	}
+	meanings := make([]string, 0, len(w.Senses))
+	for _, s := range w.Senses {
+		meanings = append(meanings, s.Meaning)
+	}
+	return meanings
+}
+
+// sense picks one of the word's senses to ask about at random
+func (w Word) sense(rnd *rand.Rand) Sense {
+	if len(w.Senses) == 0 {
+		return Sense{WordID: w.ID, Meaning: w.Meaning}
+	}
+	return w.Senses[rnd.Intn(len(w.Senses))]
+}
+
+func setupSenses(ws *website.Website, wordlistPOSTAPI *mux.Router) {
+	moveMeanings := !ws.DB().Migrator().HasTable(&Sense{})
+	if err := ws.DB().AutoMigrate(&Sense{}); err != nil {
+		panic(err)
+	}
+	if moveMeanings {
+		if err := moveMeaningsToSenses(ws.DB()); err != nil {
+			panic(err)
+		}
+	}
+
+	wordlistPOSTAPI.HandleFunc("/senses/add/", func(w http.ResponseWriter, r *http.Request) {
+		wid, err := strconv.Atoi(r.FormValue("wid"))
+		if err != nil || wid < 1 {
+			http.Error(w, "invalid word ID", http.StatusBadRequest)
+			return
+		}
+		meaning := strings.ToLower(strings.TrimSpace(r.FormValue("meaning")))
+		if meaning == "" {
+			http.Error(w, "Cannot add a sense without a meaning", http.StatusBadRequest)
+			return
+		}
+		// examples, synonyms and antonyms sent along with the meaning are added to those the word has
+		err = ws.DB().Transaction(func(tx *gorm.DB) error {
+			if err := addSense(tx, uint(wid), meaning, strings.TrimSpace(r.FormValue("pos"))); err != nil {
+				return err
+			}
+			if err := parseWordEntryForm(r.PostForm).save(tx, uint(wid), false); err != nil {
+				return err
+			}
+			return indexWord(tx, uint(wid))
+		})
+		senseSaved(ws.DB(), w, uint(wid), err)
+	})
+
+	wordlistPOSTAPI.HandleFunc("/senses/save/", func(w http.ResponseWriter, r *http.Request) {
+		sense, err := findSense(ws.DB(), r.FormValue("sid"))
+		if err != nil {
+			senseSaved(ws.DB(), w, 0, err)
+			return
+		}
+		updates := map[string]interface{}{}
+		if _, ok := r.PostForm["meaning"]; ok {
+			meaning := strings.ToLower(strings.TrimSpace(r.PostFormValue("meaning")))
+			if meaning == "" {
+				http.Error(w, "meaning is empty", http.StatusBadRequest)
+				return
+			}
+			updates["meaning"] = meaning
+		}
+		if _, ok := r.PostForm["pos"]; ok {
+			updates["part_of_speech"] = strings.TrimSpace(r.PostFormValue("pos"))
+		}
+		position := -1
+		if p := r.PostFormValue("position"); p != "" {
+			if position, err = strconv.Atoi(p); err != nil || position < 0 {
+				http.Error(w, "position must be a number from 0", http.StatusBadRequest)
+				return
+			}
+		}
+		err = ws.DB().Transaction(func(tx *gorm.DB) error {
+			if len(updates) > 0 {
+				if err := tx.Model(sense).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			var err error
+			if position >= 0 {
+				err = orderSenses(tx, sense.WordID, sense.ID, position)
+			} else {
+				err = syncMeaning(tx, sense.WordID)
+			}
+			if err != nil {
+				return err
+			}
+			return indexWord(tx, sense.WordID)
+		})
+		senseSaved(ws.DB(), w, sense.WordID, err)
+	})
+
+	wordlistPOSTAPI.HandleFunc("/senses/delete/", func(w http.ResponseWriter, r *http.Request) {
+		sense, err := findSense(ws.DB(), r.FormValue("sid"))
+		if err != nil {
+			senseSaved(ws.DB(), w, 0, err)
+			return
+		}
+		err = ws.DB().Transaction(func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&Sense{}).Where("word_id = ?", sense.WordID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count < 2 {
+				return errLastSense
+			}
+			if err := tx.Delete(sense).Error; err != nil {
+				return err
+			}
+			if err := orderSenses(tx, sense.WordID, 0, 0); err != nil {
+				return err
+			}
+			return indexWord(tx, sense.WordID)
+		})
+		senseSaved(ws.DB(), w, sense.WordID, err)
+	})
+}
+
+// senseSaved refreshes the word's senses in the cache and reports how the change went
+func senseSaved(db *gorm.DB, w http.ResponseWriter, wordID uint, err error) {
+	switch {
+	case errors.Is(err, errSenseNotFound), errors.Is(err, errLastSense), errors.Is(err, errDuplicateSense):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("error saving sense: %v", err)
+		http.Error(w, "unable to save", http.StatusInternalServerError)
+		return
+	}
+	if err := wordIndex.refresh(db, wordID); err != nil {
+		log.Printf("WARNING: unable to update word in the word cache: %v", err)
+	}
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Write([]byte("{}"))
+}
+
+func findSense(db *gorm.DB, sidStr string) (*Sense, error) {
+	sid, err := strconv.Atoi(sidStr)
+	if err != nil {
+		return nil, errSenseNotFound
+	}
+	sense := &Sense{}
+	result := db.Limit(1).Find(sense, sid)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errSenseNotFound
+	}
+	return sense, nil
+}
+
+// addSense gives the word another sense after those it has
+func addSense(tx *gorm.DB, wordID uint, meaning, partOfSpeech string) error {
+	if err := ensurePrimarySense(tx, wordID); err != nil {
+		return err
+	}
+	senses := []Sense{}
+	if err := tx.Where("word_id = ?", wordID).Order("position").Find(&senses).Error; err != nil {
+		return err
+	}
+	if len(senses) == 0 {
+		return fmt.Errorf("%w: word %d has no senses", errSenseNotFound, wordID)
+	}
+	for _, s := range senses {
+		if s.Meaning == meaning {
+			return errDuplicateSense
+		}
+	}
+	return tx.Create(&Sense{
+		WordID:       wordID,
+		Meaning:      meaning,
+		PartOfSpeech: partOfSpeech,
+		Position:     senses[len(senses)-1].Position + 1,
+	}).Error
+}
+
+// ensurePrimarySense makes the meaning of a word stored without senses, e.g. before senses existed or
+// straight into the DB, its primary sense
+func ensurePrimarySense(tx *gorm.DB, wordID uint) error {
+	var count int64
+	if err := tx.Model(&Sense{}).Where("word_id = ?", wordID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	w := &Word{}
+	result := tx.Limit(1).Find(w, wordID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: word %d not found", errSenseNotFound, wordID)
+	}
+	return tx.Create(&Sense{WordID: wordID, Meaning: w.Meaning}).Error
+}
+
+// orderSenses numbers the word's senses from 0, moving the given sense, if any, to the given position
+func orderSenses(tx *gorm.DB, wordID, moveID uint, to int) error {
+	senses := []Sense{}
+	if err := tx.Where("word_id = ?", wordID).Order("position").Find(&senses).Error; err != nil {
+		return err
+	}
+	if moveID != 0 {
+		for i, s := range senses {
+			if s.ID != moveID {
+				continue
+			}
+			senses = append(senses[:i], senses[i+1:]...)
+			if to > len(senses) {
+				to = len(senses)
+			}
+			senses = append(senses[:to], append([]Sense{s}, senses[to:]...)...)
+			break
+		}
+	}
+	for i, s := range senses {
+		if s.Position == i {
+			continue
+		}
+		if err := tx.Model(&Sense{}).Where("id = ?", s.ID).Update("position", i).Error; err != nil {
+			return err
+		}
+	}
+	return syncMeaning(tx, wordID)
+}
+
+// syncMeaning mirrors the word's primary sense in Word.Meaning
+func syncMeaning(tx *gorm.DB, wordID uint) error {
+	primary := &Sense{}
+	result := tx.Where("word_id = ?", wordID).Order("position").Limit(1).Find(primary)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return tx.Model(&Word{ID: wordID}).Update("meaning", primary.Meaning).Error
+}
+
+// moveMeaningsToSenses makes the meaning of each word from before senses existed its primary sense
+func moveMeaningsToSenses(db *gorm.DB) error {
+	words := []Word{}
+	if result := db.Find(&words); result.Error != nil {
+		return result.Error
+	}
+	senses := make([]Sense, 0, len(words))
+	for _, w := range words {
+		senses = append(senses, Sense{WordID: w.ID, Meaning: w.Meaning})
+	}
+	if len(senses) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(senses, 100).Error
+}
@@ -0,0 +1,55 @@
+package wordlist
+
+import "gorm.io/gorm"
+
+// SkippedWord is a word the learner answered "I don't know" to, or left unanswered
+type SkippedWord struct {
+	ID      uint `gorm:"primaryKey"`
+	Session string
+	WordID  int
+	Word    Word
+}
+
+// separateSkippedWords moves the skips recorded as incorrect words before skipping was told apart
+// from answering wrong; only skips confirmed by the quiz's recorded answers are moved
+func separateSkippedWords(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		skipped := []IncorrectWord{}
+		result := tx.Table("incorrect_words").
+			Select("incorrect_words.*").
+			Joins("join completed_quiz_answers on completed_quiz_answers.session = incorrect_words.session " +
+				"and completed_quiz_answers.word_id = incorrect_words.word_id").
+			Where("completed_quiz_answers.skipped").
+			Find(&skipped)
+		if result.Error != nil || len(skipped) == 0 {
+			return result.Error
+		}
+		perSession := map[string]int{}
+		ids := make([]uint, 0, len(skipped))
+		sws := make([]SkippedWord, 0, len(skipped))
+		for _, iw := range skipped {
+			perSession[iw.Session]++
+			ids = append(ids, iw.ID)
+			sws = append(sws, SkippedWord{Session: iw.Session, WordID: iw.WordID})
+		}
+		if result := tx.Create(sws); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Where("id IN ?", ids).Delete(&IncorrectWord{}); result.Error != nil {
+			return result.Error
+		}
+		if result := tx.Model(&CompletedQuizAnswer{}).Where("skipped").Update("outcome", outcomeSkipped); result.Error != nil {
+			return result.Error
+		}
+		for session, count := range perSession {
+			result := tx.Model(&CompletedQuiz{}).Where("session = ?", session).Updates(map[string]interface{}{
+				"incorrect_questions": gorm.Expr("incorrect_questions - ?", count),
+				"skipped_questions":   count,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package wordlist
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// responseMillis checks the time the client reports the learner took to answer against how long the
+// question can have been shown going by when answers reached the server, which stands in for
+// reports that are missing or too long
+func (oq *OngoingQuiz) responseMillis(oqq *OngoingQuizQuestion, reported int, at time.Time) int {
+	shown := at.Sub(oq.askedAt(oqq, at))
+	if reported <= 0 || time.Duration(reported)*time.Millisecond > shown+timeLimitGrace {
+		return int(shown / time.Millisecond)
+	}
+	return reported
+}
+
+// slowWords are the words the user takes longest to answer correctly, slowest first
+func slowWords(db *gorm.DB, userID uint, limit int) ([]SlowWord, error) {
+	slow := []SlowWord{}
+	result := db.Table("completed_quiz_answers").
+		Select("completed_quiz_answers.word_id, words.word, words.meaning, "+
+			"count(*) as correct_answers, cast(avg(completed_quiz_answers.response_millis) as integer) as average_millis").
+		Joins("join completed_quizzes on completed_quizzes.session = completed_quiz_answers.session").
+		Joins("join words on words.id = completed_quiz_answers.word_id").
+		Where("completed_quizzes.user_id = ? AND completed_quiz_answers.correct AND completed_quiz_answers.response_millis > 0", userID).
+		Group("completed_quiz_answers.word_id").
+		Order("average_millis desc").
+		Limit(limit).
+		Scan(&slow)
+	return slow, result.Error
+}
+
+type SlowWord struct {
+	WordID         int
+	Word           string
+	Meaning        string
+	CorrectAnswers int
+	AverageMillis  int // to answer correctly
+}
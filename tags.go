@@ -0,0 +1,293 @@
+package wordlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arunsworld/wordlist/pkg/website"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var (
+	errTagNotFound = errors.New("tag not found")
+	errInvalidTag  = errors.New("invalid tag")
+)
+
+// Tag groups words, e.g. "gre" or "science", to list and quiz them together
+type Tag struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"unique"`
+	CreatedAt time.Time
+}
+
+// migrateTags must run before words are migrated, which creates the table linking them to tags
+func migrateTags(db *gorm.DB) {
+	if err := db.AutoMigrate(&Tag{}); err != nil {
+		panic(err)
+	}
+	if err := db.SetupJoinTable(&Word{}, "Tags", &WordTag{}); err != nil {
+		panic(err)
+	}
+}
+
+func setupTags(ws *website.Website, wordlistAPI, wordlistPOSTAPI *mux.Router) {
+	wordlistAPI.HandleFunc("/tags/", func(w http.ResponseWriter, r *http.Request) {
+		writeTagCounts(ws.DB(), w)
+	})
+
+	wordlistPOSTAPI.HandleFunc("/tags/add/", func(w http.ResponseWriter, r *http.Request) {
+		name, err := tagName(r.FormValue("name"))
+		if err != nil {
+			tagSaved(ws.DB(), w, err, nil)
+			return
+		}
+		result := ws.DB().Limit(1).Find(&Tag{}, "name = ?", name)
+		if result.Error == nil && result.RowsAffected > 0 {
+			tagSaved(ws.DB(), w, fmt.Errorf("%w: %s already exists", errInvalidTag, name), nil)
+			return
+		}
+		err = result.Error
+		if err == nil {
+			err = ws.DB().Create(&Tag{Name: name}).Error
+		}
+		tagSaved(ws.DB(), w, err, nil)
+	})
+
+	wordlistPOSTAPI.HandleFunc("/tags/rename/", func(w http.ResponseWriter, r *http.Request) {
+		tag, err := findTag(ws.DB(), r.FormValue("tid"))
+		if err != nil {
+			tagSaved(ws.DB(), w, err, nil)
+			return
+		}
+		name, err := tagName(r.FormValue("name"))
+		if err != nil {
+			tagSaved(ws.DB(), w, err, nil)
+			return
+		}
+		result := ws.DB().Limit(1).Find(&Tag{}, "name = ? AND id != ?", name, tag.ID)
+		if result.Error == nil && result.RowsAffected > 0 {
+			tagSaved(ws.DB(), w, fmt.Errorf("%w: %s already exists", errInvalidTag, name), nil)
+			return
+		}
+		err = result.Error
+		if err == nil {
+			err = ws.DB().Model(tag).Update("name", name).Error
+		}
+		tagSaved(ws.DB(), w, err, []uint{})
+	})
+
+	wordlistPOSTAPI.HandleFunc("/tags/delete/", func(w http.ResponseWriter, r *http.Request) {
+		tag, err := findTag(ws.DB(), r.FormValue("tid"))
+		if err != nil {
+			tagSaved(ws.DB(), w, err, nil)
+			return
+		}
+		err = ws.DB().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("tag_id = ?", tag.ID).Delete(&WordTag{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(tag).Error
+		})
+		tagSaved(ws.DB(), w, err, []uint{})
+	})
+
+	// tagging and untagging take the word IDs as a comma separated "wids" list
+	wordlistPOSTAPI.HandleFunc("/tags/tag/", func(w http.ResponseWriter, r *http.Request) {
+		tag, wordIDs, err := tagAndWords(ws.DB(), r)
+		if err == nil {
+			err = ws.DB().Transaction(func(tx *gorm.DB) error {
+				for _, id := range wordIDs {
+					wt := WordTag{WordID: id, TagID: tag.ID}
+					if err := tx.Where(wt).FirstOrCreate(&wt).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		tagSaved(ws.DB(), w, err, wordIDs)
+	})
+
+	wordlistPOSTAPI.HandleFunc("/tags/untag/", func(w http.ResponseWriter, r *http.Request) {
+		tag, wordIDs, err := tagAndWords(ws.DB(), r)
+		if err == nil {
+			err = ws.DB().Where("tag_id = ? AND word_id IN ?", tag.ID, wordIDs).Delete(&WordTag{}).Error
+		}
+		tagSaved(ws.DB(), w, err, wordIDs)
+	})
+}
+
+// WordTag links a word to a tag
+type WordTag struct {
+	WordID    uint `gorm:"primaryKey"`
+	TagID     uint `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+}
+
+type TagCount struct {
+	ID    uint
+	Name  string
+	Words int
+}
+
+func tagCounts(db *gorm.DB) ([]TagCount, error) {
+	counts := []TagCount{}
+	result := db.Model(&Tag{}).
+		Select("tags.id, tags.name, count(word_tags.word_id) as words").
+		Joins("left join word_tags on word_tags.tag_id = tags.id").
+		Group("tags.id, tags.name").
+		Order("tags.name").
+		Scan(&counts)
+	return counts, result.Error
+}
+
+func writeTagCounts(db *gorm.DB, w http.ResponseWriter) {
+	counts, err := tagCounts(db)
+	if err != nil {
+		log.Printf("error reading tags from DB: %v", err)
+		http.Error(w, "unable to read tags", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/javascript")
+	if err := json.NewEncoder(w).Encode(counts); err != nil {
+		log.Printf("error encoding tags: %v", err)
+		http.Error(w, "unable to read tags", http.StatusInternalServerError)
+		return
+	}
+}
+
+// tagSaved updates the words whose tags changed in the cache, reloading it all for an empty list,
+// and reports how the change went
+func tagSaved(db *gorm.DB, w http.ResponseWriter, err error, changed []uint) {
+	switch {
+	case errors.Is(err, errTagNotFound), errors.Is(err, errInvalidTag):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("error saving tag: %v", err)
+		http.Error(w, "unable to save tag", http.StatusInternalServerError)
+		return
+	}
+	if changed != nil && len(changed) == 0 {
+		if err := wordIndex.reload(db); err != nil {
+			log.Printf("WARNING: unable to reload the word cache: %v", err)
+		}
+	}
+	for _, id := range changed {
+		if err := wordIndex.refresh(db, id); err != nil {
+			log.Printf("WARNING: unable to update word in the word cache: %v", err)
+		}
+	}
+	writeTagCounts(db, w)
+}
+
+func tagName(s string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" || strings.Contains(name, ",") {
+		return "", fmt.Errorf("%w: a tag needs a name without commas", errInvalidTag)
+	}
+	return name, nil
+}
+
+func findTag(db *gorm.DB, tidStr string) (*Tag, error) {
+	tid, err := strconv.Atoi(tidStr)
+	if err != nil {
+		return nil, errTagNotFound
+	}
+	tag := &Tag{}
+	result := db.Limit(1).Find(tag, tid)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errTagNotFound
+	}
+	return tag, nil
+}
+
+// tagAndWords reads the tag, by "tid" or "name", and the existing words of "wids" to tag or untag
+func tagAndWords(db *gorm.DB, r *http.Request) (*Tag, []uint, error) {
+	var tag *Tag
+	var err error
+	if name := r.FormValue("name"); name != "" && r.FormValue("tid") == "" {
+		tag = &Tag{}
+		result := db.Limit(1).Find(tag, "name = ?", strings.ToLower(strings.TrimSpace(name)))
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, nil, errTagNotFound
+		}
+	} else if tag, err = findTag(db, r.FormValue("tid")); err != nil {
+		return nil, nil, err
+	}
+	ids := []int{}
+	for _, s := range strings.Split(r.FormValue("wids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || id < 1 {
+			return nil, nil, fmt.Errorf("%w: wids must be a comma separated list of word IDs", errInvalidTag)
+		}
+		ids = append(ids, id)
+	}
+	words, err := wordIndex.byIDs(db, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	wordIDs := make([]uint, 0, len(words))
+	for _, w := range words {
+		wordIDs = append(wordIDs, w.ID)
+	}
+	if len(wordIDs) == 0 {
+		return nil, nil, fmt.Errorf("%w: none of the words exist", errInvalidTag)
+	}
+	return tag, wordIDs, nil
+}
+
+// parseTags reads a comma separated list of tag names
+func parseTags(s string) []string {
+	tags := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" && !contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// checkTags makes sure all the tags exist
+func checkTags(db *gorm.DB, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	found := []string{}
+	if result := db.Model(&Tag{}).Where("name IN ?", tags).Pluck("name", &found); result.Error != nil {
+		return result.Error
+	}
+	for _, t := range tags {
+		if !contains(found, t) {
+			return fmt.Errorf("%w: %s", errTagNotFound, t)
+		}
+	}
+	return nil
+}
+
+// tagged keeps the words carrying any of the tags
+func tagged(words []Word, tags []string) []Word {
+	result := make([]Word, 0, len(words))
+	for _, w := range words {
+		for _, t := range w.Tags {
+			if contains(tags, t.Name) {
+				result = append(result, w)
+				break
+			}
+		}
+	}
+	return result
+}
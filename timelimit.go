@@ -0,0 +1,64 @@
+package wordlist
+
+import (
+	"errors"
+	"time"
+)
+
+// timeLimitGrace allows for the time answers take to reach the server
+const timeLimitGrace = 2 * time.Second
+
+var errTimeUp = errors.New("time is up")
+
+func (oq *OngoingQuiz) timed() bool {
+	return oq.TimeLimit > 0 || oq.QuestionTimeLimit > 0
+}
+
+// deadline is when time is up for the whole quiz; a limit per question adds up to a limit for the quiz
+func (oq *OngoingQuiz) deadline() time.Time {
+	limit := time.Duration(oq.TimeLimit) * time.Second
+	perQuestion := time.Duration(oq.QuestionTimeLimit*len(oq.OngoingQuizQuestions)) * time.Second
+	if perQuestion > 0 && (limit == 0 || perQuestion < limit) {
+		limit = perQuestion
+	}
+	return oq.CreatedAt.Add(limit)
+}
+
+// inTime reports whether an answer to the question arriving at t counts. With a limit per question the
+// answer must come within the limit of the answer given before it, or of the start of the quiz.
+func (oq *OngoingQuiz) inTime(oqq *OngoingQuizQuestion, t time.Time) bool {
+	if !oq.timed() {
+		return true
+	}
+	if t.After(oq.deadline().Add(timeLimitGrace)) {
+		return false
+	}
+	if oq.QuestionTimeLimit == 0 {
+		return true
+	}
+	return !t.After(oq.askedAt(oqq, t).Add(time.Duration(oq.QuestionTimeLimit)*time.Second + timeLimitGrace))
+}
+
+// askedAt is when the learner got to the question answered at t at the latest: the last time another
+// question was answered before, or the start of the quiz
+func (oq *OngoingQuiz) askedAt(oqq *OngoingQuizQuestion, t time.Time) time.Time {
+	asked := oq.CreatedAt
+	for _, other := range oq.OngoingQuizQuestions {
+		if other.ID == oqq.ID || !other.Answered || other.AnsweredAt == nil {
+			continue
+		}
+		if other.AnsweredAt.After(asked) && other.AnsweredAt.Before(t) {
+			asked = *other.AnsweredAt
+		}
+	}
+	return asked
+}
+
+// secondsLeft is how long is left for the whole quiz as of now
+func (oq *OngoingQuiz) secondsLeft(now time.Time) int {
+	left := int(oq.deadline().Sub(now).Round(time.Second).Seconds())
+	if left < 0 {
+		return 0
+	}
+	return left
+}
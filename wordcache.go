@@ -0,0 +1,162 @@
+package wordlist
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// wordIndex is the word list shared by the handlers, loaded from the DB on first use
+var wordIndex = &wordCache{}
+
+// wordCache keeps the whole word list in memory; handlers that add or change words write through it
+type wordCache struct {
+	mu     sync.RWMutex
+	loaded bool
+	words  []Word       // ordered by ID
+	index  map[uint]int // ID -> position in words
+
+	hits    uint64
+	misses  uint64
+	reloads uint64
+}
+
+type WordCacheStats struct {
+	Words   int
+	Loaded  bool
+	Hits    uint64 // reads served from memory
+	Misses  uint64 // reads that had to load the word list first
+	Reloads uint64 // forced reloads
+}
+
+// all returns a copy of the word list ordered by ID, down to each word's senses and other entries,
+// so callers may change what they get without changing the cache
+func (c *wordCache) all(db *gorm.DB) ([]Word, error) {
+	c.mu.RLock()
+	if c.loaded {
+		defer c.mu.RUnlock()
+		atomic.AddUint64(&c.hits, 1)
+		return c.copyWords(), nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		atomic.AddUint64(&c.misses, 1)
+		if err := c.load(db); err != nil {
+			return nil, err
+		}
+	} else {
+		atomic.AddUint64(&c.hits, 1)
+	}
+	return c.copyWords(), nil
+}
+
+// byIDs returns the words with the given IDs that exist, in no particular order
+func (c *wordCache) byIDs(db *gorm.DB, ids []int) ([]Word, error) {
+	all, err := c.all(db)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[uint(id)] = struct{}{}
+	}
+	result := make([]Word, 0, len(ids))
+	for _, w := range all {
+		if _, ok := wanted[w.ID]; ok {
+			result = append(result, w)
+		}
+	}
+	return result, nil
+}
+
+func (c *wordCache) reload(db *gorm.DB) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	atomic.AddUint64(&c.reloads, 1)
+	return c.load(db)
+}
+
+// refresh re-reads a word after it was added or changed, dropping it if it's gone
+func (c *wordCache) refresh(db *gorm.DB, id uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.loaded {
+		// the next read loads the change along with everything else
+		return nil
+	}
+	found := []Word{}
+	if result := withEntries(db).Where("id = ?", id).Limit(1).Find(&found); result.Error != nil {
+		return result.Error
+	}
+	pos, cached := c.index[id]
+	switch {
+	case len(found) == 1 && cached:
+		c.words[pos] = found[0]
+	case len(found) == 1:
+		c.words = append(c.words, found[0])
+		sort.Slice(c.words, func(i, j int) bool { return c.words[i].ID < c.words[j].ID })
+		c.reindex()
+	case cached:
+		c.words = append(c.words[:pos], c.words[pos+1:]...)
+		c.reindex()
+	}
+	return nil
+}
+
+func (c *wordCache) stats() WordCacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return WordCacheStats{
+		Words:   len(c.words),
+		Loaded:  c.loaded,
+		Hits:    atomic.LoadUint64(&c.hits),
+		Misses:  atomic.LoadUint64(&c.misses),
+		Reloads: atomic.LoadUint64(&c.reloads),
+	}
+}
+
+// load must be called with the write lock held
+func (c *wordCache) load(db *gorm.DB) error {
+	loaded := []Word{}
+	if result := withEntries(db).Order("id").Find(&loaded); result.Error != nil {
+		return result.Error
+	}
+	c.words = loaded
+	c.reindex()
+	c.loaded = true
+	return nil
+}
+
+// withEntries loads everything the word list has on each word along with it
+func withEntries(db *gorm.DB) *gorm.DB {
+	byPosition := func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}
+	return db.Preload("Senses", byPosition).Preload("Examples", byPosition).Preload("Relations").Preload("Tags")
+}
+
+// copyWords must be called with a lock held
+func (c *wordCache) copyWords() []Word {
+	words := make([]Word, len(c.words))
+	for i, w := range c.words {
+		// copies keep loaded but empty entries empty rather than nil, as they are sent as JSON
+		w.Senses = append(make([]Sense, 0, len(w.Senses)), w.Senses...)
+		w.Examples = append(make([]WordExample, 0, len(w.Examples)), w.Examples...)
+		w.Relations = append(make([]WordRelation, 0, len(w.Relations)), w.Relations...)
+		w.Tags = append(make([]Tag, 0, len(w.Tags)), w.Tags...)
+		words[i] = w
+	}
+	return words
+}
+
+func (c *wordCache) reindex() {
+	c.index = make(map[uint]int, len(c.words))
+	for i, w := range c.words {
+		c.index[w.ID] = i
+	}
+}
@@ -0,0 +1,32 @@
+package wordlist
+
+import "testing"
+
+func TestWordCacheAllCopiesEntries(t *testing.T) {
+	c := &wordCache{loaded: true, words: []Word{{
+		ID:       1,
+		Word:     "word1",
+		Senses:   []Sense{{ID: 1, Meaning: "first"}, {ID: 2, Meaning: "second"}},
+		Examples: []WordExample{{ID: 1, Sentence: "an example"}},
+		Tags:     []Tag{{ID: 1, Name: "tag"}},
+	}}}
+	c.reindex()
+	words, err := c.all(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := words[0]
+	w.Senses[0], w.Senses[1] = w.Senses[1], w.Senses[0]
+	w.Examples[0].Sentence = "changed"
+	w.Tags[0].Name = "changed"
+	w.Relations = append(w.Relations, WordRelation{Text: "added"})
+
+	cached := c.words[0]
+	if cached.Senses[0].Meaning != "first" || cached.Examples[0].Sentence != "an example" ||
+		cached.Tags[0].Name != "tag" || len(cached.Relations) != 0 {
+		t.Fatalf("changing what all returned changed the cache: %+v", cached)
+	}
+	if words, _ := c.all(nil); words[0].Relations == nil {
+		t.Fatal("empty entries should copy as empty, not nil")
+	}
+}
@@ -15,6 +15,12 @@ type Word struct {
 	ID      uint   `gorm:"primaryKey"`
 	Word    string `gorm:"unique"`
 	Meaning string
+	Example string  // a sentence using the word
+	Senses  []Sense `gorm:"constraint:OnDelete:CASCADE;"` // ordered by position
+
+	Examples  []WordExample  `gorm:"constraint:OnDelete:CASCADE;"` // ordered by position
+	Relations []WordRelation `gorm:"constraint:OnDelete:CASCADE;"`
+	Tags      []Tag          `gorm:"many2many:word_tags"`
 }
 
 func (w *Word) Exists(db *gorm.DB) bool {
@@ -22,7 +28,11 @@ func (w *Word) Exists(db *gorm.DB) bool {
 	return result.RowsAffected > 0
 }
 
+// Create adds the word, with its meaning as its primary sense unless it's given senses
 func (w *Word) Create(db *gorm.DB) error {
+	if len(w.Senses) == 0 {
+		w.Senses = []Sense{{Meaning: w.Meaning}}
+	}
 	result := db.Create(w)
 	if result.Error != nil {
 		return result.Error
@@ -30,18 +40,21 @@ func (w *Word) Create(db *gorm.DB) error {
 	return nil
 }
 
+// authorizedUsers may manage the word list and set assignments
+var authorizedUsers = []string{"admin", "abarua", "nomi", "aanya"}
+
 func SetupWordlist(ws *website.Website) {
+	migrateTags(ws.DB())
 	if err := ws.DB().AutoMigrate(&Word{}); err != nil {
 		panic(err)
 	}
+	migrateWordEntries(ws.DB())
 
 	wordlistHTML, err := ws.WebsiteContent().ReadFile("web/html/words.html")
 	if err != nil {
 		panic(err)
 	}
 
-	authorizedUsers := []string{"admin", "abarua", "nomi", "aanya"}
-
 	wordlist := ws.Router().Path("/wordlist/").Methods("GET").Subrouter()
 	wordlist.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{UserNames: authorizedUsers}))
 	wordlist.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -61,19 +74,48 @@ func SetupWordlist(ws *website.Website) {
 			return
 		}
 
+		pos := strings.TrimSpace(r.FormValue("pos"))
+		entry := parseWordEntryForm(r.PostForm)
+
 		wrd := &Word{
 			Word:    word,
 			Meaning: meaning,
+			Example: strings.TrimSpace(r.FormValue("example")),
+			Senses:  []Sense{{Meaning: meaning, PartOfSpeech: pos}},
 		}
+		// another meaning of a known word is added through the word list API, by those who manage it
 		if wrd.Exists(ws.DB()) {
 			http.Error(w, "word already exists", http.StatusBadRequest)
 			return
 		}
-		if err := wrd.Create(ws.DB()); err != nil {
+		var linked []uint
+		err := ws.DB().Transaction(func(tx *gorm.DB) error {
+			if err := wrd.Create(tx); err != nil {
+				return err
+			}
+			if err := entry.save(tx, wrd.ID, true); err != nil {
+				return err
+			}
+			var err error
+			if linked, err = linkRelations(tx, wrd); err != nil {
+				return err
+			}
+			return indexWord(tx, wrd.ID)
+		})
+		if err != nil {
 			log.Printf("error creating word: %v", err)
 			http.Error(w, "Could not create word", http.StatusInternalServerError)
 			return
 		}
+		if err := wordIndex.refresh(ws.DB(), wrd.ID); err != nil {
+			log.Printf("WARNING: unable to add new word to the word cache: %v", err)
+		}
+		// words that named it as a synonym or antonym now link to it
+		for _, id := range linked {
+			if err := wordIndex.refresh(ws.DB(), id); err != nil {
+				log.Printf("WARNING: unable to update word in the word cache: %v", err)
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/javascript")
 		w.Write([]byte("{}"))
@@ -83,13 +125,16 @@ func SetupWordlist(ws *website.Website) {
 	wordlistAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{UserNames: authorizedUsers, IsForAPI: true}))
 
 	wordlistAPI.HandleFunc("/words/", func(w http.ResponseWriter, r *http.Request) {
-		allWords := []Word{}
-		result := ws.DB().Find(&allWords)
-		if result.Error != nil {
+		allWords, err := wordIndex.all(ws.DB())
+		if err != nil {
 			log.Printf("error reading words from DB: %v", err)
 			http.Error(w, "unable to read words", http.StatusInternalServerError)
 			return
 		}
+		// words carrying any of the comma separated tags
+		if tags := parseTags(r.URL.Query().Get("tags")); len(tags) > 0 {
+			allWords = tagged(allWords, tags)
+		}
 		w.Header().Set("Content-Type", "application/javascript")
 		if err := json.NewEncoder(w).Encode(allWords); err != nil {
 			log.Printf("error marshaling data as JSON: %v", err)
@@ -98,6 +143,15 @@ func SetupWordlist(ws *website.Website) {
 		}
 	})
 
+	wordlistAPI.HandleFunc("/cache/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(wordIndex.stats()); err != nil {
+			log.Printf("error encoding word cache stats: %v", err)
+			http.Error(w, "unable to read word cache stats", http.StatusInternalServerError)
+			return
+		}
+	})
+
 	wordlistPOSTAPI := ws.Router().PathPrefix("/wordlist-api/").Methods("POST").Subrouter()
 	wordlistPOSTAPI.Use(ws.EnsureAuthMiddleware(website.AuthMiddlewareConfig{UserNames: authorizedUsers, IsForAPI: true}))
 
@@ -120,13 +174,52 @@ func SetupWordlist(ws *website.Website) {
 		word := &Word{
 			ID: uint(wid),
 		}
-		result := ws.DB().Model(word).Update("meaning", meaning)
-		if result.Error != nil {
-			log.Printf("error saving word update: %v", result.Error)
+		err = ws.DB().Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(word).Update("meaning", meaning).Error; err != nil {
+				return err
+			}
+			if err := parseWordEntryForm(r.PostForm).save(tx, word.ID, true); err != nil {
+				return err
+			}
+			senseUpdates := map[string]interface{}{"meaning": meaning}
+			if _, ok := r.PostForm["pos"]; ok {
+				senseUpdates["part_of_speech"] = strings.TrimSpace(r.PostFormValue("pos"))
+			}
+			if err := ensurePrimarySense(tx, word.ID); err != nil {
+				return err
+			}
+			if err := tx.Model(&Sense{}).Where("word_id = ? AND position = 0", word.ID).Updates(senseUpdates).Error; err != nil {
+				return err
+			}
+			return indexWord(tx, word.ID)
+		})
+		if err != nil {
+			log.Printf("error saving word update: %v", err)
 			http.Error(w, "unable to save", http.StatusInternalServerError)
 			return
 		}
+		if err := wordIndex.refresh(ws.DB(), word.ID); err != nil {
+			log.Printf("WARNING: unable to update word in the word cache: %v", err)
+		}
 		w.Header().Set("Content-Type", "application/javascript")
 		w.Write([]byte("{}"))
 	})
+
+	wordlistPOSTAPI.HandleFunc("/cache/reload/", func(w http.ResponseWriter, r *http.Request) {
+		if err := wordIndex.reload(ws.DB()); err != nil {
+			log.Printf("error reloading word cache: %v", err)
+			http.Error(w, "unable to reload words", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/javascript")
+		if err := json.NewEncoder(w).Encode(wordIndex.stats()); err != nil {
+			log.Printf("error encoding word cache stats: %v", err)
+			http.Error(w, "unable to read word cache stats", http.StatusInternalServerError)
+			return
+		}
+	})
+
+	setupSenses(ws, wordlistPOSTAPI)
+	setupTags(ws, wordlistAPI, wordlistPOSTAPI)
+	setupSearch(ws, wordlistAPI)
 }